@@ -4,11 +4,13 @@ package agents
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
+	"github.com/biodoia/golem/internal/providers"
 	"github.com/biodoia/golem/pkg/zhipu"
 )
 
@@ -183,15 +185,15 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 
 // Coordinator orchestrates multiple agents
 type Coordinator struct {
-	client   *zhipu.Client
+	provider providers.Provider
 	agents   map[AgentType]*Agent
 	registry *ToolRegistry
 }
 
-// NewCoordinator creates a new agent coordinator
-func NewCoordinator(client *zhipu.Client) *Coordinator {
+// NewCoordinator creates a new agent coordinator on top of a provider
+func NewCoordinator(provider providers.Provider) *Coordinator {
 	return &Coordinator{
-		client:   client,
+		provider: provider,
 		agents:   DefaultAgents(),
 		registry: NewToolRegistry(),
 	}
@@ -289,6 +291,8 @@ func (c *Coordinator) RegisterBuiltinTools() {
 			return string(output), nil
 		},
 	)
+
+	c.RegisterWebSearchTool()
 }
 
 // Task represents a task for agents
@@ -296,6 +300,7 @@ type Task struct {
 	Description string
 	Context     string
 	Files       []string
+	ForceTool   string // Tool the agent must call first, e.g. "read_file"
 }
 
 // Result represents an agent's output
@@ -317,10 +322,10 @@ func (c *Coordinator) Run(ctx context.Context, agentType AgentType, task Task) (
 		{Role: "user", Content: formatTask(task)},
 	}
 
-	resp, err := c.client.Chat(ctx, &zhipu.ChatRequest{
+	resp, err := c.provider.Chat(ctx, &zhipu.ChatRequest{
 		Model:       agent.Model,
 		Messages:    messages,
-		Temperature: agent.Temperature,
+		Temperature: zhipu.Float64(agent.Temperature),
 		MaxTokens:   agent.MaxTokens,
 	})
 	if err != nil {
@@ -339,6 +344,49 @@ func (c *Coordinator) Run(ctx context.Context, agentType AgentType, task Task) (
 	}, nil
 }
 
+// RunStream executes a task with a specific agent, passing each streamed
+// event to onEvent as it arrives
+func (c *Coordinator) RunStream(ctx context.Context, agentType AgentType, task Task, onEvent func(zhipu.StreamEvent)) (*Result, error) {
+	agent, ok := c.agents[agentType]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent: %s", agentType)
+	}
+
+	stream, err := c.provider.ChatStream(ctx, &zhipu.ChatRequest{
+		Model: agent.Model,
+		Messages: []zhipu.Message{
+			{Role: "system", Content: agent.SystemPrompt},
+			{Role: "user", Content: formatTask(task)},
+		},
+		Temperature: zhipu.Float64(agent.Temperature),
+		MaxTokens:   agent.MaxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed: %w", agent.Name, err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for stream.Next() {
+		ev := stream.Event()
+		if ev.Type == zhipu.EventTextDelta {
+			content.WriteString(ev.Text)
+		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("agent %s failed: %w", agent.Name, err)
+	}
+
+	result := &Result{Agent: agentType, Content: content.String()}
+	if usage := stream.Usage(); usage != nil {
+		result.Tokens = usage.TotalTokens
+	}
+	return result, nil
+}
+
 // RunWithTools executes a task with an agent that can use tools
 // Implements the tool call loop: request → tool_calls → execute → continue
 func (c *Coordinator) RunWithTools(ctx context.Context, agentType AgentType, task Task) (*Result, error) {
@@ -360,21 +408,35 @@ func (c *Coordinator) RunWithTools(ctx context.Context, agentType AgentType, tas
 
 	var totalTokens int
 	maxIterations := 10 // Prevent infinite loops
+	compacted := false
+
+	// Forcing only applies to the first turn, or the loop would never end
+	toolChoice := zhipu.ToolChoiceAuto()
+	if task.ForceTool != "" {
+		toolChoice = zhipu.ForceTool(task.ForceTool)
+	}
 
 	for i := 0; i < maxIterations; i++ {
-		resp, err := c.client.Chat(ctx, &zhipu.ChatRequest{
+		resp, err := c.provider.Chat(ctx, &zhipu.ChatRequest{
 			Model:       agent.Model,
 			Messages:    messages,
-			Temperature: agent.Temperature,
+			Temperature: zhipu.Float64(agent.Temperature),
 			MaxTokens:   agent.MaxTokens,
 			Tools:       tools,
-			ToolChoice:  "auto",
+			ToolChoice:  toolChoice,
 		})
+		if errors.Is(err, zhipu.ErrContextLength) && !compacted {
+			// Drop old tool output once and try again before giving up
+			messages = compactToolResults(messages)
+			compacted = true
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("agent %s failed: %w", agent.Name, err)
 		}
 
 		totalTokens += resp.Usage.TotalTokens
+		toolChoice = zhipu.ToolChoiceAuto()
 
 		if len(resp.Choices) == 0 {
 			return nil, fmt.Errorf("no response from agent")
@@ -454,6 +516,24 @@ func (c *Coordinator) Plan(ctx context.Context, task Task) ([]Result, error) {
 	return results, nil
 }
 
+// compactToolResults elides tool output from all but the latest tool round
+func compactToolResults(messages []zhipu.Message) []zhipu.Message {
+	lastAssistant := -1
+	for i, msg := range messages {
+		if msg.Role == "assistant" {
+			lastAssistant = i
+		}
+	}
+	compacted := make([]zhipu.Message, len(messages))
+	copy(compacted, messages)
+	for i := 0; i < lastAssistant; i++ {
+		if compacted[i].Role == "tool" {
+			compacted[i].Content = "[tool output elided to fit the context window]"
+		}
+	}
+	return compacted
+}
+
 // formatTask formats a task for the agent
 func formatTask(task Task) string {
 	result := task.Description
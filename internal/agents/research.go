@@ -0,0 +1,137 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/biodoia/golem/internal/providers"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// ResearchReport accumulates a rumination run: its reasoning, the pages it
+// consulted and its final answer
+type ResearchReport struct {
+	Question  string
+	Answer    string
+	Reasoning string
+	Sources   []zhipu.WebSearchResult // Unique by link, in consultation order
+	Usage     *zhipu.Usage
+}
+
+// StartResearch streams a rumination run on question. Feed its events to
+// a ResearchReport with Add. The rumination model and its web search are
+// Z.AI services, so provider must be Z.AI.
+func StartResearch(ctx context.Context, provider providers.Provider, question string) (*zhipu.Stream, error) {
+	if _, ok := providers.ZhipuClient(provider); !ok {
+		return nil, fmt.Errorf("research needs the Z.AI provider")
+	}
+	return provider.ChatStream(ctx, &zhipu.ChatRequest{
+		Model: zhipu.ModelGLMZ1Rumination,
+		Messages: []zhipu.Message{
+			{Role: "user", Content: question},
+		},
+		Tools: []zhipu.Tool{{
+			Type:      "web_search",
+			WebSearch: &zhipu.WebSearch{Enable: true, SearchResult: true},
+		}},
+		SearchMode: zhipu.SearchEnginePro,
+	})
+}
+
+// Add records one stream event and returns the sources it added, so
+// search steps can be shown as they happen
+func (r *ResearchReport) Add(ev zhipu.StreamEvent) []zhipu.WebSearchResult {
+	switch ev.Type {
+	case zhipu.EventTextDelta:
+		r.Answer += ev.Text
+	case zhipu.EventReasoningDelta:
+		r.Reasoning += ev.Text
+	case zhipu.EventWebSearch:
+		return r.addSources(ev.WebSearch)
+	case zhipu.EventUsage:
+		r.Usage = ev.Usage
+	}
+	return nil
+}
+
+func (r *ResearchReport) addSources(results []zhipu.WebSearchResult) []zhipu.WebSearchResult {
+	seen := make(map[string]bool, len(r.Sources))
+	for _, s := range r.Sources {
+		seen[s.Link] = true
+	}
+	var added []zhipu.WebSearchResult
+	for _, res := range results {
+		if res.Link != "" && !seen[res.Link] {
+			seen[res.Link] = true
+			added = append(added, res)
+		}
+	}
+	r.Sources = append(r.Sources, added...)
+	return added
+}
+
+// refCitation matches the provider's citation markers, e.g. [ref_3]
+var refCitation = regexp.MustCompile(`\[ref_(\d+)\]`)
+
+// Markdown renders the report with numbered citations and a source list.
+// Citations of pages the provider referenced as ref_N are renumbered to
+// match the list.
+func (r *ResearchReport) Markdown() string {
+	numbers := make(map[string]int)
+	for i, s := range r.Sources {
+		if s.Refer != "" {
+			numbers[s.Refer] = i + 1
+		}
+	}
+	answer := refCitation.ReplaceAllStringFunc(r.Answer, func(m string) string {
+		ref := "ref_" + refCitation.FindStringSubmatch(m)[1]
+		if n, ok := numbers[ref]; ok {
+			return "[" + strconv.Itoa(n) + "]"
+		}
+		return m
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Research: %s\n\n%s\n", r.Question, strings.TrimSpace(answer))
+	if len(r.Sources) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		for i, s := range r.Sources {
+			title := s.Title
+			if title == "" {
+				title = s.Link
+			}
+			fmt.Fprintf(&b, "%d. [%s](%s)", i+1, title, s.Link)
+			if s.Media != "" {
+				fmt.Fprintf(&b, " — %s", s.Media)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Research runs a rumination session to completion, passing each event to
+// onEvent (which may be nil) as it arrives
+func (c *Coordinator) Research(ctx context.Context, question string, onEvent func(zhipu.StreamEvent)) (*ResearchReport, error) {
+	stream, err := StartResearch(ctx, c.provider, question)
+	if err != nil {
+		return nil, fmt.Errorf("research failed: %w", err)
+	}
+	defer stream.Close()
+
+	report := &ResearchReport{Question: question}
+	for stream.Next() {
+		ev := stream.Event()
+		if onEvent != nil {
+			onEvent(ev)
+		}
+		report.Add(ev)
+	}
+	if err := stream.Err(); err != nil {
+		return report, fmt.Errorf("research failed: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,100 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// ArchitectPlan is the architect's plan in machine-readable form
+type ArchitectPlan struct {
+	Summary string     `json:"summary" description:"One paragraph overview of the design"`
+	Steps   []PlanStep `json:"steps" description:"Ordered implementation milestones"`
+	Risks   []string   `json:"risks,omitempty" description:"Open questions and risks"`
+}
+
+// PlanStep is one milestone of an ArchitectPlan
+type PlanStep struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Files       []string `json:"files,omitempty" description:"Files to create or change"`
+}
+
+// Markdown renders the plan for humans and for the next agent's context
+func (p *ArchitectPlan) Markdown() string {
+	var b strings.Builder
+	b.WriteString(p.Summary + "\n")
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "\n%d. **%s**: %s", i+1, step.Title, step.Description)
+		if len(step.Files) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(step.Files, ", "))
+		}
+	}
+	if len(p.Risks) > 0 {
+		b.WriteString("\n\n## Risks\n")
+		for _, r := range p.Risks {
+			b.WriteString("- " + r + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Review verdicts
+const (
+	VerdictApprove        = "approve"
+	VerdictRequestChanges = "request_changes"
+)
+
+// ReviewVerdict is the reviewer's decision in machine-readable form
+type ReviewVerdict struct {
+	Verdict string        `json:"verdict" enum:"approve,request_changes"`
+	Summary string        `json:"summary"`
+	Issues  []ReviewIssue `json:"issues,omitempty"`
+}
+
+// ReviewIssue is one problem found by the reviewer
+type ReviewIssue struct {
+	Severity    string `json:"severity" enum:"critical,major,minor,nit"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Description string `json:"description"`
+}
+
+// Approved reports whether the reviewer accepted the change
+func (v *ReviewVerdict) Approved() bool {
+	return v.Verdict == VerdictApprove
+}
+
+// Architect asks the architect agent for a structured plan
+func (c *Coordinator) Architect(ctx context.Context, task Task) (*ArchitectPlan, error) {
+	return runStructured[ArchitectPlan](ctx, c, AgentArchitect, task)
+}
+
+// Review asks the reviewer agent for a structured verdict
+func (c *Coordinator) Review(ctx context.Context, task Task) (*ReviewVerdict, error) {
+	return runStructured[ReviewVerdict](ctx, c, AgentReviewer, task)
+}
+
+// runStructured runs a task with an agent in JSON mode and decodes the reply
+func runStructured[T any](ctx context.Context, c *Coordinator, agentType AgentType, task Task) (*T, error) {
+	agent, ok := c.agents[agentType]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent: %s", agentType)
+	}
+
+	out, err := zhipu.ChatJSON[T](ctx, c.provider, &zhipu.ChatRequest{
+		Model: agent.Model,
+		Messages: []zhipu.Message{
+			{Role: "system", Content: agent.SystemPrompt},
+			{Role: "user", Content: formatTask(task)},
+		},
+		Temperature: zhipu.Float64(agent.Temperature),
+		MaxTokens:   agent.MaxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed: %w", agent.Name, err)
+	}
+	return &out, nil
+}
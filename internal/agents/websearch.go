@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/biodoia/golem/internal/providers"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// WebSearchToolName is the function name of the web search tool
+const WebSearchToolName = "web_search"
+
+// maxSearchResults caps how many results one web_search call may return
+const maxSearchResults = 10
+
+// WebSearchTool returns the web_search function definition
+func WebSearchTool() zhipu.Tool {
+	recency := zhipu.StringProp("Only return pages published within this period")
+	recency.Enum = []string{zhipu.RecencyDay, zhipu.RecencyWeek, zhipu.RecencyMonth, zhipu.RecencyYear, zhipu.RecencyAny}
+
+	return zhipu.NewFunctionTool(WebSearchToolName,
+		"Search the web for current information. Results are numbered; cite them as [n] and list the links of the sources you used at the end of your answer.",
+		zhipu.NewObjectSchema(
+			map[string]*zhipu.JSONSchema{
+				"query":   zhipu.StringProp("The search query"),
+				"domain":  zhipu.StringProp("Only return pages from this domain, e.g. go.dev"),
+				"recency": recency,
+				"count":   zhipu.IntProp(fmt.Sprintf("Number of results, 1-%d (default 5)", maxSearchResults)),
+			},
+			[]string{"query"},
+		))
+}
+
+// WebSearchHandler runs web_search calls with client
+func WebSearchHandler(client *zhipu.Client) ToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		query, _ := args["query"].(string)
+		if query == "" {
+			return "", fmt.Errorf("query is required")
+		}
+		domain, _ := args["domain"].(string)
+		recency, _ := args["recency"].(string)
+		count := 5
+		if n, ok := args["count"].(float64); ok && n >= 1 {
+			count = min(int(n), maxSearchResults)
+		}
+
+		resp, err := client.WebSearch(ctx, &zhipu.WebSearchRequest{
+			SearchQuery:         query,
+			Count:               count,
+			SearchDomainFilter:  domain,
+			SearchRecencyFilter: recency,
+		})
+		if err != nil {
+			return "", err
+		}
+		return zhipu.FormatWebSearchResults(resp.SearchResult), nil
+	}
+}
+
+// RegisterWebSearchTool makes web_search available to agents. Web search
+// is a Z.AI API, so the tool is only registered on the Z.AI provider.
+func (c *Coordinator) RegisterWebSearchTool() {
+	if client, ok := providers.ZhipuClient(c.provider); ok {
+		c.RegisterTool(WebSearchTool(), WebSearchHandler(client))
+	}
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// batchIDPrefix marks custom IDs built from file paths
+const batchIDPrefix = "golem-"
+
+const batchUsage = `Usage:
+  golem batch submit -glob PATTERN (-prompt TEMPLATE | -prompt-file FILE) [-model M] [-wait]
+  golem batch status ID
+  golem batch results ID
+  golem batch cancel ID
+  golem batch list
+
+The prompt is a text/template with {{.Path}}, {{.Name}} and {{.Content}}.`
+
+// batchFile is the data a batch prompt template is executed with
+type batchFile struct {
+	Path    string
+	Name    string
+	Content string
+}
+
+// RunBatch runs one prompt over many files with the batch API
+func RunBatch(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", batchUsage)
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if settings.APIKey == "" {
+		return fmt.Errorf("missing API key. Set ZAI_API_KEY or ZHIPU_API_KEY")
+	}
+	client := settings.NewClient()
+	ctx := context.Background()
+
+	if args[0] == "submit" {
+		return batchSubmit(ctx, client, settings.Model, args[1:])
+	}
+	if args[0] == "list" {
+		list, err := client.ListBatches(ctx, "", 20)
+		if err != nil {
+			return explainError(err)
+		}
+		for i := range list.Data {
+			printBatch(&list.Data[i])
+		}
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("%s", batchUsage)
+	}
+
+	var batch *zhipu.Batch
+	switch args[0] {
+	case "results":
+		return batchResults(ctx, client, args[1])
+	case "status":
+		batch, err = client.GetBatch(ctx, args[1])
+	case "cancel":
+		batch, err = client.CancelBatch(ctx, args[1])
+	default:
+		return fmt.Errorf("unknown batch command: %s\n%s", args[0], batchUsage)
+	}
+	if err != nil {
+		return explainError(err)
+	}
+	printBatch(batch)
+	return nil
+}
+
+func batchSubmit(ctx context.Context, client *zhipu.Client, model string, args []string) error {
+	fs := flag.NewFlagSet("batch submit", flag.ContinueOnError)
+	glob := fs.String("glob", "", "files to process, e.g. 'internal/*/*.go'")
+	prompt := fs.String("prompt", "", "prompt template")
+	promptFile := fs.String("prompt-file", "", "read the prompt template from a file")
+	fs.StringVar(&model, "model", model, "model to use")
+	wait := fs.Bool("wait", false, "wait for the batch and print the results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if model == "" {
+		return fmt.Errorf("%w: pass -model", config.ErrNoModel)
+	}
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		*prompt = string(data)
+	}
+	if *glob == "" || *prompt == "" {
+		return fmt.Errorf("%s", batchUsage)
+	}
+	tmpl, err := template.New("prompt").Parse(*prompt)
+	if err != nil {
+		return fmt.Errorf("parse prompt template: %w", err)
+	}
+
+	paths, err := filepath.Glob(*glob)
+	if err != nil {
+		return err
+	}
+	var input zhipu.BatchInput
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // Directories and unreadable files are skipped
+		}
+		var text bytes.Buffer
+		if err := tmpl.Execute(&text, batchFile{Path: path, Name: filepath.Base(path), Content: string(data)}); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		err = input.Add(batchIDPrefix+path, &zhipu.ChatRequest{
+			Model:    model,
+			Messages: []zhipu.Message{{Role: "user", Content: text.String()}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if input.Len() == 0 {
+		return fmt.Errorf("no files match %s", *glob)
+	}
+
+	fileID, err := client.UploadBatchInput(ctx, &input)
+	if err != nil {
+		return explainError(err)
+	}
+	batch, err := client.CreateBatch(ctx, &zhipu.CreateBatchRequest{
+		InputFileID: fileID,
+		Metadata:    map[string]string{"source": "golem", "glob": *glob},
+	})
+	if err != nil {
+		return explainError(err)
+	}
+	fmt.Fprintf(os.Stderr, "Submitted %d requests as batch %s\n", input.Len(), batch.ID)
+	if !*wait {
+		fmt.Println(batch.ID)
+		return nil
+	}
+
+	batch, err = client.WaitBatch(ctx, batch.ID, 30*time.Second)
+	if err != nil {
+		return explainError(err)
+	}
+	return batchResults(ctx, client, batch.ID)
+}
+
+// batchResults prints each file's reply as a markdown section
+func batchResults(ctx context.Context, client *zhipu.Client, batchID string) error {
+	batch, err := client.GetBatch(ctx, batchID)
+	if err != nil {
+		return explainError(err)
+	}
+	if !batch.Done() {
+		return fmt.Errorf("batch %s is still %s", batch.ID, batch.Status)
+	}
+
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		content, err := client.BatchOutput(ctx, fileID)
+		if err != nil {
+			return explainError(err)
+		}
+		results, err := zhipu.ParseBatchResults(content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			name := strings.TrimPrefix(res.CustomID, batchIDPrefix)
+			if res.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, res.Err)
+				continue
+			}
+			text := ""
+			if len(res.Response.Choices) > 0 {
+				text, _ = res.Response.Choices[0].Message.Content.(string)
+			}
+			fmt.Printf("## %s\n\n%s\n\n", name, text)
+		}
+	}
+	return nil
+}
+
+func printBatch(b *zhipu.Batch) {
+	fmt.Printf("%s\t%s\t%d/%d done, %d failed\t%s\n",
+		b.ID, b.Status, b.RequestCounts.Completed, b.RequestCounts.Total, b.RequestCounts.Failed,
+		time.Unix(b.CreatedAt, 0).Format("2006-01-02 15:04"))
+}
@@ -3,15 +3,57 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/internal/providers"
+	"github.com/biodoia/golem/internal/tools"
 	"github.com/biodoia/golem/pkg/zhipu"
 )
 
 func RunOneShot(query string) error {
-	return RunOneShotStream(query)
+	return explainError(RunOneShotStream(query))
+}
+
+// explainError adds a remediation hint to API errors the user can act on
+func explainError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, zhipu.ErrAuth):
+		return fmt.Errorf("%w\nCheck ZAI_API_KEY / ZHIPU_API_KEY or api_key in ~/.golem/settings.json", err)
+	case errors.Is(err, zhipu.ErrQuotaExceeded):
+		return fmt.Errorf("%w\nYour Z.AI balance or daily quota is exhausted", err)
+	case errors.Is(err, zhipu.ErrContextLength):
+		return fmt.Errorf("%w\nShorten the query or the files attached to it", err)
+	case errors.Is(err, zhipu.ErrContentFiltered):
+		return fmt.Errorf("%w\nThe request or reply was blocked by the provider's content filter", err)
+	case errors.Is(err, config.ErrNoModel):
+		return fmt.Errorf("%w\nAdd a \"model\" to the provider's entry under \"providers\" in ~/.golem/settings.json", err)
+	}
+	return err
+}
+
+// chatRouter returns a router over the provider selected by settings, as
+// the TUI uses, reporting rerouted requests on stderr. The provider may be
+// missing when settings.Model names another, as "ollama/llama3.2" does.
+func chatRouter(settings config.Settings) (*providers.Router, error) {
+	if settings.Model == "" {
+		return nil, fmt.Errorf("%w for provider %q", config.ErrNoModel, settings.Provider)
+	}
+	primary, err := providers.New(settings)
+	if err != nil {
+		if p, _, ferr := providers.ForModel(settings, nil, settings.Model); ferr != nil || p == nil {
+			return nil, err
+		}
+	}
+	router := providers.NewRouter(settings, primary)
+	router.OnDecision = func(d providers.RouteDecision) {
+		fmt.Fprintf(os.Stderr, "[Route: %s]\n", d)
+	}
+	return router, nil
 }
 
 // RunOneShot executes a query with streaming output
@@ -20,49 +62,54 @@ func RunOneShotStream(query string) error {
 	if err != nil {
 		return err
 	}
-	if settings.APIKey == "" {
-		return fmt.Errorf("missing API key. Set ZAI_API_KEY or ZHIPU_API_KEY")
+	router, err := chatRouter(settings)
+	if err != nil {
+		return err
+	}
+
+	// @image:path attachments are routed to a vision model
+	msg := zhipu.Message{Role: "user", Content: query}
+	if text, images := tools.ParseAttachments(query); len(images) > 0 {
+		if msg, err = zhipu.NewImageMessage(text, images...); err != nil {
+			return err
+		}
 	}
-	client := zhipu.NewClient(settings.APIKey)
 
 	ctx := context.Background()
-	textCh, errCh := client.ChatStream(ctx, &zhipu.ChatRequest{
+	stream, err := router.ChatStream(ctx, &zhipu.ChatRequest{
 		Model:    settings.Model,
-		Messages: []zhipu.Message{{Role: "user", Content: query}},
+		Messages: []zhipu.Message{msg},
 	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
 
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				fmt.Println() // End with newline
-				return nil
-			}
-			if text != "" {
-				fmt.Print(text)
-			}
-		case err, ok := <-errCh:
-			if ok && err != nil {
-				return err
-			}
-			fmt.Println()
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
+	for stream.Next() {
+		if ev := stream.Event(); ev.Type == zhipu.EventTextDelta {
+			fmt.Print(ev.Text)
 		}
 	}
+	fmt.Println() // End with newline
+	return stream.Err()
 }
 
 // RunOneShotTools executes a query with function calling support
 func RunOneShotTools(query string, tools []zhipu.Tool, toolHandlers map[string]func(map[string]interface{}) (string, error)) error {
+	return RunOneShotToolsForced(query, "", tools, toolHandlers)
+}
+
+// RunOneShotToolsForced is RunOneShotTools with the first call forced to
+// the named tool (empty lets the model choose)
+func RunOneShotToolsForced(query string, firstTool string, tools []zhipu.Tool, toolHandlers map[string]func(map[string]interface{}) (string, error)) error {
 	settings, err := config.Load()
 	if err != nil {
 		return err
 	}
-	if settings.APIKey == "" {
-		return fmt.Errorf("missing API key. Set ZAI_API_KEY or ZHIPU_API_KEY")
+	router, err := chatRouter(settings)
+	if err != nil {
+		return err
 	}
-	client := zhipu.NewClient(settings.APIKey)
 
 	messages := []zhipu.Message{{Role: "user", Content: query}}
 	maxToolCalls := 5 // Prevent infinite loops
@@ -70,13 +117,19 @@ func RunOneShotTools(query string, tools []zhipu.Tool, toolHandlers map[string]f
 
 	ctx := context.Background()
 
+	toolChoice := zhipu.ToolChoiceAuto()
+	if firstTool != "" {
+		toolChoice = zhipu.ForceTool(firstTool)
+	}
+
 	for {
-		resp, err := client.Chat(ctx, &zhipu.ChatRequest{
+		resp, err := router.Chat(ctx, &zhipu.ChatRequest{
 			Model:      settings.Model,
 			Messages:   messages,
 			Tools:      tools,
-			ToolChoice: "auto",
+			ToolChoice: toolChoice,
 		})
+		toolChoice = zhipu.ToolChoiceAuto()
 		if err != nil {
 			return err
 		}
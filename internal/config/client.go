@@ -0,0 +1,56 @@
+package config
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// ClientOptions translates the HTTP settings into zhipu client options
+func (s Settings) ClientOptions() []zhipu.Option {
+	var opts []zhipu.Option
+	if s.BaseURL != "" {
+		opts = append(opts, zhipu.WithBaseURL(s.BaseURL))
+	}
+	if hc := s.HTTPClient(); hc != nil {
+		opts = append(opts, zhipu.WithHTTPClient(hc))
+	}
+	if s.TimeoutSeconds > 0 {
+		opts = append(opts, zhipu.WithTimeout(time.Duration(s.TimeoutSeconds)*time.Second))
+	}
+	if s.StreamIdleSeconds > 0 {
+		opts = append(opts, zhipu.WithStreamIdleTimeout(time.Duration(s.StreamIdleSeconds)*time.Second))
+	}
+	for key, value := range s.Headers {
+		opts = append(opts, zhipu.WithHeader(key, value))
+	}
+	if s.UserAgent != "" {
+		opts = append(opts, zhipu.WithUserAgent(s.UserAgent))
+	}
+	if s.EmbeddingCache {
+		opts = append(opts, zhipu.WithEmbeddingCache(zhipu.NewEmbeddingCache(zhipu.DefaultEmbeddingCacheDir())))
+	}
+	return opts
+}
+
+// HTTPClient returns an HTTP client that goes through ProxyURL, or nil
+// when no proxy is set
+func (s Settings) HTTPClient() *http.Client {
+	if s.ProxyURL == "" {
+		return nil
+	}
+	proxy, err := url.Parse(s.ProxyURL)
+	if err != nil {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return &http.Client{Transport: transport}
+}
+
+// NewClient builds a zhipu client from the settings
+func (s Settings) NewClient() *zhipu.Client {
+	return zhipu.NewClient(s.APIKey, s.ClientOptions()...)
+}
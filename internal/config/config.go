@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 )
@@ -14,41 +16,142 @@ type Settings struct {
 	Theme        string `json:"theme"`
 	MCPConfig    string `json:"mcp_config"`
 	CommandsPath string `json:"commands_path"`
+
+	// HTTP client settings
+	BaseURL           string            `json:"base_url"`
+	ProxyURL          string            `json:"proxy_url"`
+	TimeoutSeconds    int               `json:"timeout_seconds"`
+	StreamIdleSeconds int               `json:"stream_idle_seconds"`
+	Headers           map[string]string `json:"headers"`
+	UserAgent         string            `json:"user_agent"`
+
+	// EmbeddingCache keeps embeddings under ~/.golem/embeddings
+	EmbeddingCache bool `json:"embedding_cache"`
+
+	// Providers configures backends by name, e.g. "openai" or a custom
+	// name with a type
+	Providers map[string]ProviderSettings `json:"providers"`
+
+	// Routing picks models by what a request needs
+	Routing RoutingSettings `json:"routing"`
+}
+
+// ProviderSettings configures one provider backend
+type ProviderSettings struct {
+	// Type selects the implementation when the name is not a provider
+	// itself, e.g. "vllm" with type "openai"
+	Type           string            `json:"type"`
+	BaseURL        string            `json:"base_url"`
+	APIKey         string            `json:"api_key"`
+	SecretKey      string            `json:"secret_key"`      // Second credential of vendors that sign requests or issue tokens
+	Region         string            `json:"region"`          // Cloud region, for vendors that route by it
+	Model          string            `json:"model"`           // Replaces Settings.Model while the provider is active
+	EmbeddingModel string            `json:"embedding_model"` // Default model for embeddings
+	Headers        map[string]string `json:"headers"`
+}
+
+// RoutingSettings configures the model router. Models may be written
+// "<provider>/<name>" to route to another provider.
+type RoutingSettings struct {
+	Vision    string   `json:"vision"`    // For requests with images; default: a vision model of the same provider
+	Tools     string   `json:"tools"`     // For requests with tools the model cannot call
+	Reasoning string   `json:"reasoning"` // For "think hard" requests; default on Z.AI: GLM-Z1
+	Fallback  []string `json:"fallback"`  // Tried in order when a model is rate limited or fails
+}
+
+// ActiveProvider returns the settings of the selected provider, or zero
+// settings if it has none
+func (s Settings) ActiveProvider() ProviderSettings {
+	return s.Providers[s.Provider]
+}
+
+// ErrNoModel is reported when a chat needs a model and neither the
+// settings nor the provider's defaults name one
+var ErrNoModel = errors.New("no model configured")
+
+// DefaultModel is the chat model of the default provider
+const DefaultModel = "glm-4-32b-0414"
+
+// providerModels are the chat models used when the settings name none, by
+// provider type. OpenAI-compatible endpoints serve whatever was deployed
+// on them, so they have no default.
+var providerModels = map[string]string{
+	"zai":         DefaultModel,
+	"ollama":      "llama3.2",
+	"dashscope":   "qwen-plus",
+	"hunyuan":     "hunyuan-turbo",
+	"spark":       "generalv3.5",
+	"wenxin":      "ernie-3.5-8k",
+	"siliconflow": "deepseek-ai/DeepSeek-V3",
 }
 
 func DefaultSettings() Settings {
 	return Settings{
-		Model:    "glm-4-32b-0414",
+		Model:    DefaultModel,
 		Provider: "zai",
 		Theme:    "cyberpunk",
 	}
 }
 
+// Load reads ~/.golem/settings.json over the defaults. GOLEM_PROVIDER
+// overrides the file's provider; without a configured model, the
+// provider's default model is used. Model is left empty when the provider
+// has no default, for the caller to report when a chat needs one.
 func Load() (Settings, error) {
 	settings := DefaultSettings()
+	settings.Model = "" // Chosen once the provider is known
 	if apiKey := os.Getenv("ZAI_API_KEY"); apiKey != "" {
 		settings.APIKey = apiKey
 	}
 	if apiKey := os.Getenv("ZHIPU_API_KEY"); apiKey != "" {
 		settings.APIKey = apiKey
 	}
+	if baseURL := os.Getenv("ZAI_BASE_URL"); baseURL != "" {
+		settings.BaseURL = baseURL
+	}
 
 	path := filepath.Join(os.Getenv("HOME"), ".golem", "settings.json")
 	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return settings, nil
-		}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return settings, err
 	}
+	if err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return settings, err
+		}
+	}
+	if settings.ProxyURL != "" {
+		if _, err := url.Parse(settings.ProxyURL); err != nil {
+			return settings, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+	}
 
-	if err := json.Unmarshal(data, &settings); err != nil {
-		return settings, err
+	if provider := os.Getenv("GOLEM_PROVIDER"); provider != "" {
+		settings.Provider = provider
+	}
+	if model := settings.ActiveProvider().Model; model != "" {
+		settings.Model = model
+	}
+	if settings.Model == "" {
+		settings.Model = defaultModel(settings)
 	}
 
 	return settings, nil
 }
 
+// defaultModel returns the default chat model of the selected provider,
+// or "" if it has none
+func defaultModel(settings Settings) string {
+	kind := settings.Provider
+	if typ := settings.ActiveProvider().Type; typ != "" {
+		kind = typ
+	}
+	if kind == "" {
+		kind = "zai"
+	}
+	return providerModels[kind]
+}
+
 func CommandsSearchPaths(custom string) []string {
 	paths := []string{
 		filepath.Join(os.Getenv("HOME"), ".golem", "commands"),
@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// catalogue lists the models of the hosted vendors other than Z.AI. /model
+// offers them as "<provider>/<id>", which ForModel routes to the vendor.
+var catalogue = []ModelInfo{
+	// Alibaba Cloud DashScope (Tongyi Qianwen)
+	{Provider: "dashscope", ID: "qwen-max", Name: "Qwen-Max", Description: "Strongest Qwen, complex tasks", Capabilities: []string{"chat", "code", "function_calling"}, Context: 32768},
+	{Provider: "dashscope", ID: "qwen-plus", Name: "Qwen-Plus", Description: "Balanced quality, speed and cost", Capabilities: []string{"chat", "code", "function_calling"}, Context: 131072},
+	{Provider: "dashscope", ID: "qwen-turbo", Name: "Qwen-Turbo", Description: "Fast and cheap, long context", Capabilities: []string{"chat", "function_calling"}, Context: 1000000},
+	{Provider: "dashscope", ID: "qwen-vl-max", Name: "Qwen-VL-Max", Description: "Vision model for image understanding", Capabilities: []string{"vision", "image_analysis"}, Context: 32768},
+	{Provider: "dashscope", ID: "qwq-plus", Name: "QwQ-Plus", Description: "Deep thinking, math, reasoning", Capabilities: []string{"reasoning", "math", "analysis"}, Context: 131072},
+
+	// Tencent Hunyuan
+	{Provider: "hunyuan", ID: "hunyuan-turbo", Name: "Hunyuan-Turbo", Description: "Flagship Hunyuan model", Capabilities: []string{"chat", "code", "function_calling"}, Context: 32768},
+	{Provider: "hunyuan", ID: "hunyuan-functioncall", Name: "Hunyuan-FunctionCall", Description: "Tuned for tool use", Capabilities: []string{"chat", "function_calling"}, Context: 32768},
+	{Provider: "hunyuan", ID: "hunyuan-standard", Name: "Hunyuan-Standard", Description: "Balanced quality and cost", Capabilities: []string{"chat"}, Context: 32768},
+	{Provider: "hunyuan", ID: "hunyuan-lite", Name: "Hunyuan-Lite", Description: "Free tier, long context", Capabilities: []string{"chat"}, Context: 262144},
+	{Provider: "hunyuan", ID: "hunyuan-vision", Name: "Hunyuan-Vision", Description: "Vision model for image understanding", Capabilities: []string{"vision", "image_analysis"}, Context: 8192},
+	{Provider: "hunyuan", ID: "hunyuan-t1-latest", Name: "Hunyuan-T1", Description: "Deep thinking, math, reasoning", Capabilities: []string{"reasoning", "math", "analysis"}, Context: 32768},
+
+	// iFlytek Spark
+	{Provider: "spark", ID: "4.0Ultra", Name: "Spark 4.0 Ultra", Description: "Strongest Spark model", Capabilities: []string{"chat", "code", "function_calling"}, Context: 8192},
+	{Provider: "spark", ID: "generalv3.5", Name: "Spark Max", Description: "General purpose", Capabilities: []string{"chat", "function_calling"}, Context: 8192},
+	{Provider: "spark", ID: "max-32k", Name: "Spark Max-32K", Description: "General purpose, longer context", Capabilities: []string{"chat", "function_calling"}, Context: 32768},
+	{Provider: "spark", ID: "pro-128k", Name: "Spark Pro-128K", Description: "Long documents", Capabilities: []string{"chat"}, Context: 131072},
+	{Provider: "spark", ID: "lite", Name: "Spark Lite", Description: "Free tier, lightweight", Capabilities: []string{"chat"}, Context: 4096},
+
+	// Baidu Wenxin (ERNIE)
+	{Provider: "wenxin", ID: "ernie-4.0-8k", Name: "ERNIE 4.0", Description: "Strongest ERNIE model", Capabilities: []string{"chat", "code", "function_calling"}, Context: 8192},
+	{Provider: "wenxin", ID: "ernie-4.0-turbo-8k", Name: "ERNIE 4.0 Turbo", Description: "Faster ERNIE 4.0", Capabilities: []string{"chat", "function_calling"}, Context: 8192},
+	{Provider: "wenxin", ID: "ernie-3.5-8k", Name: "ERNIE 3.5", Description: "General purpose", Capabilities: []string{"chat", "function_calling"}, Context: 8192},
+	{Provider: "wenxin", ID: "ernie-speed-128k", Name: "ERNIE Speed", Description: "Free tier, long context", Capabilities: []string{"chat"}, Context: 131072},
+	{Provider: "wenxin", ID: "ernie-lite-8k", Name: "ERNIE Lite", Description: "Free tier, lightweight", Capabilities: []string{"chat"}, Context: 8192},
+
+	// SiliconFlow aggregates open models behind one OpenAI-compatible API
+	{Provider: "siliconflow", ID: "deepseek-ai/DeepSeek-V3", Name: "DeepSeek-V3", Description: "Dialogue, code, function calling", Capabilities: []string{"chat", "code", "function_calling"}, Context: 65536},
+	{Provider: "siliconflow", ID: "deepseek-ai/DeepSeek-R1", Name: "DeepSeek-R1", Description: "Deep thinking, math, reasoning", Capabilities: []string{"reasoning", "math", "analysis"}, Context: 65536},
+	{Provider: "siliconflow", ID: "Qwen/Qwen2.5-72B-Instruct", Name: "Qwen2.5-72B", Description: "Open Qwen, function calling", Capabilities: []string{"chat", "code", "function_calling"}, Context: 32768},
+	{Provider: "siliconflow", ID: "Qwen/Qwen2.5-VL-72B-Instruct", Name: "Qwen2.5-VL-72B", Description: "Open vision model", Capabilities: []string{"vision", "image_analysis"}, Context: 32768},
+	{Provider: "siliconflow", ID: "THUDM/glm-4-9b-chat", Name: "GLM-4-9B", Description: "Free tier GLM", Capabilities: []string{"chat", "function_calling"}, Context: 32768},
+}
+
+// Catalogue returns the catalogue models of provider, or all of them when
+// provider is empty
+func Catalogue(provider string) []ModelInfo {
+	var models []ModelInfo
+	for _, m := range catalogue {
+		if provider == "" || m.Provider == provider {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// catalogueModel looks up a model written "<provider>/<id>"
+func catalogueModel(modelID string) *ModelInfo {
+	provider, id, ok := strings.Cut(modelID, "/")
+	if !ok {
+		return nil
+	}
+	for _, m := range catalogue {
+		if m.Provider == provider && m.ID == id {
+			return &m
+		}
+	}
+	return nil
+}
+
+// ContextWindow returns the context size in tokens of model, as named to
+// p, or 0 if unknown. Models of other vendors are looked up in the
+// catalogue, as "<provider>/<id>" or as an ID of p.
+func ContextWindow(p Provider, model string) int {
+	if n := zhipu.ContextWindow(model); n > 0 {
+		return n
+	}
+	if info := GetModelInfo(model); info != nil {
+		return info.Context
+	}
+	if p != nil {
+		if info := GetModelInfo(p.Name() + "/" + model); info != nil {
+			return info.Context
+		}
+	}
+	return 0
+}
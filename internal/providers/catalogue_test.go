@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func TestCatalogue(t *testing.T) {
+	for _, name := range []string{"dashscope", "hunyuan", "spark", "wenxin", "siliconflow"} {
+		models := Catalogue(name)
+		if len(models) == 0 {
+			t.Errorf("no catalogue entries for %s", name)
+		}
+		if !isProviderName(config.Settings{}, name) {
+			t.Errorf("%s is not registered", name)
+		}
+		for _, m := range models {
+			if m.Provider != name || m.Context == 0 || len(m.Capabilities) == 0 {
+				t.Errorf("incomplete entry %+v", m)
+			}
+		}
+	}
+}
+
+func TestGetModelInfo_Catalogue(t *testing.T) {
+	info := GetModelInfo("siliconflow/Qwen/Qwen2.5-VL-72B-Instruct")
+	if info == nil || info.Provider != "siliconflow" || info.Capabilities[0] != "vision" {
+		t.Errorf("GetModelInfo = %+v", info)
+	}
+	if info := GetModelInfo("glm-4-32b-0414"); info == nil || info.Provider != DefaultProvider {
+		t.Errorf("GetModelInfo(glm-4-32b-0414) = %+v", info)
+	}
+	if info := GetModelInfo("dashscope/nope"); info != nil {
+		t.Errorf("GetModelInfo(dashscope/nope) = %+v", info)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	spark := NewSpark("spark", config.ProviderSettings{})
+	tests := []struct {
+		p     Provider
+		model string
+		want  int
+	}{
+		{nil, "glm-4-32b-0414", 128000},
+		{nil, "spark/lite", 4096},
+		{spark, "lite", 4096},
+		{spark, "unknown", 0},
+		{nil, "lite", 0},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.p, tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%v, %q) = %d, want %d", tt.p, tt.model, got, tt.want)
+		}
+	}
+
+	// A long chat is trimmed to the vendor model's window
+	messages := make([]zhipu.Message, 200)
+	for i := range messages {
+		messages[i] = zhipu.Message{Role: "user", Content: strings.Repeat("word ", 50)}
+	}
+	kept, dropped := zhipu.FitWindow(messages, ContextWindow(spark, "lite"), 1024)
+	if dropped == 0 || zhipu.EstimateMessages(kept)+1024 > 4096 {
+		t.Errorf("kept %d messages (%d tokens), dropped %d", len(kept), zhipu.EstimateMessages(kept), dropped)
+	}
+}
+
+func TestForModel_Vendors(t *testing.T) {
+	t.Setenv("DASHSCOPE_API_KEY", "sk-test")
+	t.Setenv("SILICONFLOW_API_KEY", "sk-test")
+	settings := config.Settings{APIKey: "k"}
+	def, err := New(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		model, provider, name string
+	}{
+		{"dashscope/qwen-max", "dashscope", "qwen-max"},
+		{"siliconflow/deepseek-ai/DeepSeek-V3", "siliconflow", "deepseek-ai/DeepSeek-V3"},
+	}
+	for _, tt := range tests {
+		p, name, err := ForModel(settings, def, tt.model)
+		if err != nil {
+			t.Errorf("ForModel(%q): %v", tt.model, err)
+			continue
+		}
+		if p.Name() != tt.provider || name != tt.name {
+			t.Errorf("ForModel(%q) = %s, %q; want %s, %q", tt.model, p.Name(), name, tt.provider, tt.name)
+		}
+	}
+}
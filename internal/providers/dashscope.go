@@ -0,0 +1,399 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// DashScopeBaseURL is Alibaba Cloud's DashScope API, which serves Qwen
+const DashScopeBaseURL = "https://dashscope.aliyuncs.com/api/v1"
+
+// DashScope endpoints; vision models ("-vl") use the multimodal one
+const (
+	dashScopeTextPath       = "/services/aigc/text-generation/generation"
+	dashScopeMultimodalPath = "/services/aigc/multimodal-generation/generation"
+	dashScopeEmbeddingPath  = "/services/embeddings/text-embedding/text-embedding"
+
+	dashScopeEmbeddingModel = "text-embedding-v3"
+)
+
+// dashScopeCodes maps DashScope error codes to the zhipu categories
+var dashScopeCodes = map[string]error{
+	"InvalidApiKey":              zhipu.ErrAuth,
+	"Throttling":                 zhipu.ErrRateLimited,
+	"Throttling.RateQuota":       zhipu.ErrRateLimited,
+	"Throttling.AllocationQuota": zhipu.ErrRateLimited,
+	"Arrearage":                  zhipu.ErrQuotaExceeded,
+	"DataInspectionFailed":       zhipu.ErrContentFiltered,
+	"InternalError":              zhipu.ErrServer,
+}
+
+func init() {
+	Register("dashscope", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("DASHSCOPE_API_KEY")
+		}
+		if ps.APIKey == "" {
+			return nil, fmt.Errorf("missing DashScope API key. Set DASHSCOPE_API_KEY")
+		}
+		p := NewDashScope(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// DashScope is the Provider for Alibaba Cloud's Tongyi Qianwen models,
+// using the native DashScope API rather than its OpenAI-compatible mode,
+// which lacks the multimodal endpoint.
+type DashScope struct {
+	httpBackend
+	apiKey         string
+	embeddingModel string
+}
+
+// NewDashScope creates a provider called name for the account in ps
+func NewDashScope(name string, ps config.ProviderSettings) *DashScope {
+	p := &DashScope{
+		httpBackend:    newHTTPBackend(name, ps, DashScopeBaseURL),
+		apiKey:         ps.APIKey,
+		embeddingModel: ps.EmbeddingModel,
+	}
+	if p.embeddingModel == "" {
+		p.embeddingModel = dashScopeEmbeddingModel
+	}
+	return p
+}
+
+type dashScopeRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Messages []dashScopeMessage `json:"messages"`
+	} `json:"input"`
+	Parameters dashScopeParameters `json:"parameters"`
+}
+
+type dashScopeParameters struct {
+	ResultFormat      string                `json:"result_format"`
+	IncrementalOutput bool                  `json:"incremental_output,omitempty"`
+	Temperature       *float64              `json:"temperature,omitempty"`
+	TopP              *float64              `json:"top_p,omitempty"`
+	MaxTokens         int                   `json:"max_tokens,omitempty"`
+	Stop              []string              `json:"stop,omitempty"`
+	Seed              *int                  `json:"seed,omitempty"`
+	Tools             []zhipu.Tool          `json:"tools,omitempty"`
+	ToolChoice        *zhipu.ToolChoice     `json:"tool_choice,omitempty"`
+	ResponseFormat    *zhipu.ResponseFormat `json:"response_format,omitempty"`
+}
+
+// dashScopeMessage carries text models' string content, or the list of
+// {"text": ...} and {"image": ...} items multimodal models take
+type dashScopeMessage struct {
+	Role       string              `json:"role"`
+	Content    interface{}         `json:"content"`
+	ToolCalls  []dashScopeToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+	Name       string              `json:"name,omitempty"` // Function name of a tool result
+}
+
+type dashScopeToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type dashScopeResponse struct {
+	RequestID string `json:"request_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Output    struct {
+		Choices []struct {
+			FinishReason string `json:"finish_reason"`
+			Message      struct {
+				Role             string              `json:"role"`
+				Content          json.RawMessage     `json:"content"`
+				ReasoningContent string              `json:"reasoning_content"`
+				ToolCalls        []dashScopeToolCall `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func isDashScopeVision(model string) bool {
+	return strings.Contains(model, "-vl")
+}
+
+// toDashScopeRequest translates req. Vision models get their content as
+// text and image items; greedy decoding becomes temperature 0.
+func toDashScopeRequest(req *zhipu.ChatRequest) *dashScopeRequest {
+	out := &dashScopeRequest{Model: req.Model}
+	vision := isDashScopeVision(req.Model)
+
+	callNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		dm := dashScopeMessage{Role: msg.Role, Content: msg.Text(), ToolCallID: msg.ToolCallID}
+		if vision {
+			var items []map[string]string
+			for _, url := range msg.ImageURLs() {
+				items = append(items, map[string]string{"image": url})
+			}
+			dm.Content = append(items, map[string]string{"text": msg.Text()})
+		}
+		for i, tc := range msg.ToolCalls {
+			call := dashScopeToolCall{Index: i, ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = tc.Function.Arguments
+			dm.ToolCalls = append(dm.ToolCalls, call)
+			callNames[tc.ID] = tc.Function.Name
+		}
+		if msg.Role == "tool" {
+			dm.Name = callNames[msg.ToolCallID]
+		}
+		out.Input.Messages = append(out.Input.Messages, dm)
+	}
+
+	params := &out.Parameters
+	params.ResultFormat = "message"
+	params.IncrementalOutput = req.Stream
+	params.Temperature = req.Temperature
+	params.TopP = req.TopP
+	params.MaxTokens = req.MaxTokens
+	params.Stop = req.Stop
+	params.Seed = req.Seed
+	params.ResponseFormat = req.ResponseFormat
+	for _, tool := range req.Tools {
+		if tool.Type == "function" {
+			params.Tools = append(params.Tools, tool)
+		}
+	}
+	if len(params.Tools) > 0 {
+		params.ToolChoice = req.ToolChoice
+	}
+	if req.DoSample != nil && !*req.DoSample && req.Temperature == nil {
+		params.Temperature = zhipu.Float64(0)
+	}
+	return out
+}
+
+func (r *dashScopeResponse) usage() *zhipu.Usage {
+	return &zhipu.Usage{
+		PromptTokens:     r.Usage.InputTokens,
+		CompletionTokens: r.Usage.OutputTokens,
+		TotalTokens:      r.Usage.InputTokens + r.Usage.OutputTokens,
+	}
+}
+
+// dashScopeText reads content sent as a string, or as multimodal items
+func dashScopeText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var items []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &items)
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(item.Text)
+	}
+	return b.String()
+}
+
+func (c dashScopeToolCall) toolCall() zhipu.ToolCall {
+	call := zhipu.ToolCall{ID: c.ID, Type: "function"}
+	call.Function.Name = c.Function.Name
+	call.Function.Arguments = c.Function.Arguments
+	return call
+}
+
+// Chat sends a chat request and waits for the whole reply
+func (p *DashScope) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire := toDashScopeRequest(req)
+	wire.Parameters.IncrementalOutput = false
+	resp, err := p.do(ctx, p.chatPath(req.Model), wire, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var reply dashScopeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(reply.Output.Choices) == 0 {
+		return nil, fmt.Errorf("%s: response has no choices", p.name)
+	}
+	choice := reply.Output.Choices[0]
+	msg := zhipu.Message{
+		Role:             "assistant",
+		Content:          dashScopeText(choice.Message.Content),
+		ReasoningContent: choice.Message.ReasoningContent,
+	}
+	for _, tc := range choice.Message.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, tc.toolCall())
+	}
+	out := chatResponse(reply.RequestID, req.Model, msg, choice.FinishReason, *reply.usage())
+	out.RequestID = reply.RequestID
+	return out, nil
+}
+
+// ChatStream streams a chat reply
+func (p *DashScope) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	resp, err := p.do(ctx, p.chatPath(req.Model), toDashScopeRequest(req), true)
+	if err != nil {
+		return nil, err
+	}
+	return zhipu.NewSSEStream(resp.Body, req.RequestID, decodeDashScopeChunk), nil
+}
+
+// decodeDashScopeChunk converts one streamed chunk to events. Usage is
+// cumulative, so it is only reported with the finish reason; "null" means
+// the reply is not finished yet.
+func decodeDashScopeChunk(payload []byte) ([]zhipu.StreamEvent, error) {
+	var chunk dashScopeResponse
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return nil, fmt.Errorf("decode stream: %w", err)
+	}
+	if chunk.Code != "" {
+		return nil, categorize(&zhipu.APIError{
+			StatusCode: http.StatusOK,
+			Code:       chunk.Code,
+			Message:    chunk.Message,
+			RequestID:  chunk.RequestID,
+		}, dashScopeCodes)
+	}
+
+	var events []zhipu.StreamEvent
+	for _, choice := range chunk.Output.Choices {
+		if text := choice.Message.ReasoningContent; text != "" {
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventReasoningDelta, Text: text})
+		}
+		if text := dashScopeText(choice.Message.Content); text != "" {
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventTextDelta, Text: text})
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			call := tc.toolCall()
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventToolCallDelta, ToolCallIndex: tc.Index, ToolCall: &call})
+		}
+		if choice.FinishReason != "" && choice.FinishReason != "null" {
+			events = append(events,
+				zhipu.StreamEvent{Type: zhipu.EventFinish, FinishReason: choice.FinishReason},
+				zhipu.StreamEvent{Type: zhipu.EventUsage, Usage: chunk.usage()})
+		}
+	}
+	return events, nil
+}
+
+// Embeddings embeds req.Input with req.Model, or text-embedding-v3
+func (p *DashScope) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.embeddingModel
+	}
+	body := map[string]interface{}{
+		"model": model,
+		"input": map[string][]string{"texts": req.Input},
+	}
+	if req.Dimensions > 0 {
+		body["parameters"] = map[string]int{"dimension": req.Dimensions}
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	resp, err := p.do(ctx, dashScopeEmbeddingPath, body, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var wire struct {
+		Output struct {
+			Embeddings []struct {
+				TextIndex int       `json:"text_index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"embeddings"`
+		} `json:"output"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&wire); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	result := &zhipu.EmbeddingResponse{
+		Model:      model,
+		Embeddings: make([][]float32, len(req.Input)),
+		Usage:      zhipu.Usage{PromptTokens: wire.Usage.TotalTokens, TotalTokens: wire.Usage.TotalTokens},
+	}
+	for _, e := range wire.Output.Embeddings {
+		if e.TextIndex < 0 || e.TextIndex >= len(result.Embeddings) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.TextIndex)
+		}
+		result.Embeddings[e.TextIndex] = e.Embedding
+	}
+	return result, nil
+}
+
+// Models lists the Qwen models in the catalogue
+func (p *DashScope) Models(ctx context.Context) ([]ModelInfo, error) {
+	return Catalogue("dashscope"), nil
+}
+
+func (p *DashScope) chatPath(model string) string {
+	if isDashScopeVision(model) {
+		return dashScopeMultimodalPath
+	}
+	return dashScopeTextPath
+}
+
+// do posts body to path; failures become *zhipu.APIError tagged with the
+// DashScope categories. On success the caller must close resp.Body.
+func (p *DashScope) do(ctx context.Context, path string, body interface{}, stream bool) (*http.Response, error) {
+	httpReq, _, err := p.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("X-DashScope-SSE", "enable")
+	}
+	return p.send(httpReq, parseDashScopeError)
+}
+
+// parseDashScopeError reads DashScope's {"code", "message", "request_id"}
+// bodies
+func parseDashScopeError(status int, header http.Header, body []byte) error {
+	apiErr := &zhipu.APIError{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	var payload struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+		apiErr.Code = payload.Code
+		apiErr.Message = payload.Message
+		apiErr.RequestID = payload.RequestID
+	}
+	return categorize(apiErr, dashScopeCodes)
+}
@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newDashScopeTest(t *testing.T, handler http.HandlerFunc) *DashScope {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewDashScope("dashscope", config.ProviderSettings{BaseURL: server.URL, APIKey: "sk-test"})
+}
+
+func TestDashScope_ChatStream(t *testing.T) {
+	var got dashScopeRequest
+	p := newDashScopeTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashScopeTextPath {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("X-DashScope-SSE") != "enable" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "dashscope_stream.txt")
+	})
+
+	call := zhipu.ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "list_dir"
+	call.Function.Arguments = `{}`
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model: "qwen-max",
+		Messages: []zhipu.Message{
+			{Role: "user", Content: "read go.mod"},
+			{Role: "assistant", ToolCalls: []zhipu.ToolCall{call}},
+			{Role: "tool", ToolCallID: "call_1", Content: "go.mod"},
+		},
+		Tools: []zhipu.Tool{zhipu.NewFunctionTool("read_file", "Read a file", zhipu.NewObjectSchema(nil, nil))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	if !got.Parameters.IncrementalOutput || got.Parameters.ResultFormat != "message" || len(got.Parameters.Tools) != 1 {
+		t.Errorf("parameters = %+v", got.Parameters)
+	}
+	if msg := got.Input.Messages[2]; msg.Name != "list_dir" || msg.ToolCallID != "call_1" {
+		t.Errorf("tool result = %+v", msg)
+	}
+	if result.Content != "Let me read it." {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_6a0f" || result.ToolCalls[0].Function.Arguments != `{"path": "go.mod"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+	if result.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q", result.FinishReason)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 62 {
+		t.Errorf("Usage = %+v, want the final cumulative counts", result.Usage)
+	}
+}
+
+func TestDashScope_StreamError(t *testing.T) {
+	p := newDashScopeTest(t, func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "dashscope_stream_error.txt")
+	})
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{Model: "qwen-plus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if !errors.Is(result.Error, zhipu.ErrContentFiltered) {
+		t.Errorf("Error = %v, want ErrContentFiltered", result.Error)
+	}
+	if result.Content != "Sure" {
+		t.Errorf("Content = %q", result.Content)
+	}
+}
+
+func TestDashScope_Vision(t *testing.T) {
+	var got map[string]interface{}
+	p := newDashScopeTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashScopeMultimodalPath {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "dashscope_vl_chat.json")
+	})
+
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model: "qwen-vl-max",
+		Messages: []zhipu.Message{{Role: "user", Content: []zhipu.ContentPart{
+			{Type: "text", Text: "what is this?"},
+			{Type: "image_url", ImageURL: &zhipu.ImageURL{URL: "https://example.com/cat.png"}},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := json.Marshal(got["input"].(map[string]interface{})["messages"].([]interface{})[0].(map[string]interface{})["content"])
+	if string(content) != `[{"image":"https://example.com/cat.png"},{"text":"what is this?"}]` {
+		t.Errorf("content = %s", content)
+	}
+	if text, _ := resp.Choices[0].Message.Content.(string); text != "A cat on a keyboard." {
+		t.Errorf("Content = %v", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage.TotalTokens != 1257 || resp.RequestID != "ds-3" {
+		t.Errorf("Usage = %+v, RequestID = %q", resp.Usage, resp.RequestID)
+	}
+}
+
+func TestDashScope_Error(t *testing.T) {
+	p := newDashScopeTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"Arrearage","message":"Access denied, please make sure your account is in good standing.","request_id":"ds-4"}`))
+	})
+	_, err := p.Chat(context.Background(), &zhipu.ChatRequest{Model: "qwen-max"})
+	if !errors.Is(err, zhipu.ErrQuotaExceeded) {
+		t.Errorf("err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestDashScope_Embeddings(t *testing.T) {
+	p := newDashScopeTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashScopeEmbeddingPath {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"output":{"embeddings":[{"text_index":1,"embedding":[0.2]},{"text_index":0,"embedding":[0.1]}]},"usage":{"total_tokens":4}}`))
+	})
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != dashScopeEmbeddingModel || resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.2 {
+		t.Errorf("Embeddings = %+v", resp)
+	}
+}
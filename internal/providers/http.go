@@ -0,0 +1,258 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// httpBackend holds what the HTTP providers share: the endpoint, extra
+// headers, the HTTP client, the timeout of non-streaming requests and the
+// retry policy and stream idle timeout zhipu.Client uses
+type httpBackend struct {
+	name       string
+	baseURL    string
+	headers    map[string]string
+	httpClient *http.Client
+	timeout    time.Duration
+	retry      zhipu.RetryPolicy
+	streamIdle time.Duration
+}
+
+func newHTTPBackend(name string, ps config.ProviderSettings, defaultURL string) httpBackend {
+	baseURL := ps.BaseURL
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return httpBackend{
+		name:       name,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		headers:    ps.Headers,
+		httpClient: &http.Client{},
+		timeout:    zhipu.DefaultTimeout,
+		retry:      zhipu.DefaultRetryPolicy(),
+		streamIdle: zhipu.DefaultStreamIdleTimeout,
+	}
+}
+
+// configure applies the proxy and timeouts of the global settings
+func (b *httpBackend) configure(settings config.Settings) {
+	if hc := settings.HTTPClient(); hc != nil {
+		b.httpClient = hc
+	}
+	if settings.TimeoutSeconds > 0 {
+		b.timeout = time.Duration(settings.TimeoutSeconds) * time.Second
+	}
+	if settings.StreamIdleSeconds > 0 {
+		b.streamIdle = time.Duration(settings.StreamIdleSeconds) * time.Second
+	}
+}
+
+// Name returns the name the provider was configured under
+func (b *httpBackend) Name() string {
+	return b.name
+}
+
+func (b *httpBackend) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, b.timeout)
+}
+
+// newRequest builds a request to baseURL+path with body encoded as JSON.
+// It also returns the encoded body, for providers that sign it.
+func (b *httpBackend) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, []byte, error) {
+	var data []byte
+	var reader io.Reader
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, nil, fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	for key, value := range b.headers {
+		httpReq.Header.Set(key, value)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, data, nil
+}
+
+// errorParser turns the body of a failed response into an error
+type errorParser func(status int, header http.Header, body []byte) error
+
+// send sends httpReq, retrying transport errors and temporary API errors
+// as zhipu.Client does. Non-2xx responses are read and turned into an
+// error by parse, or by zhipu.ParseAPIError when parse is nil. Requests
+// that accept text/event-stream are aborted when the stream goes idle. On
+// success the caller must close resp.Body.
+func (b *httpBackend) send(httpReq *http.Request, parse errorParser) (*http.Response, error) {
+	ctx := httpReq.Context()
+	stream := httpReq.Header.Get("Accept") == "text/event-stream"
+	for attempt := 0; ; attempt++ {
+		attemptCtx, wrap, stop := ctx, func(body io.ReadCloser) io.ReadCloser { return body }, func() {}
+		if stream {
+			attemptCtx, wrap, stop = zhipu.WatchStream(ctx, b.streamIdle)
+		}
+		attemptReq := httpReq.Clone(attemptCtx)
+		if attempt > 0 && httpReq.GetBody != nil {
+			body, err := httpReq.GetBody()
+			if err != nil {
+				stop()
+				return nil, fmt.Errorf("%s: %w", b.name, err)
+			}
+			attemptReq.Body = body
+		}
+
+		var wait time.Duration
+		resp, err := b.httpClient.Do(attemptReq)
+		switch {
+		case err != nil:
+			if cause := context.Cause(attemptCtx); errors.Is(cause, zhipu.ErrStreamIdle) {
+				err = cause
+			}
+			stop()
+			if ctx.Err() != nil || attempt >= b.retry.MaxRetries {
+				return nil, fmt.Errorf("%s: %w", b.name, redactURL(err))
+			}
+			wait = b.retry.Delay(attempt, "")
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			resp.Body = wrap(resp.Body)
+			return resp, nil
+		default:
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			stop()
+			if parse != nil {
+				err = parse(resp.StatusCode, resp.Header, data)
+			} else {
+				err = zhipu.ParseAPIError(resp.StatusCode, resp.Header, data)
+			}
+			var apiErr *zhipu.APIError
+			if attempt >= b.retry.MaxRetries || !errors.As(err, &apiErr) || !apiErr.Temporary() {
+				return nil, err
+			}
+			wait = b.retry.Delay(attempt, resp.Header.Get("Retry-After"))
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// redactURL hides the query string of the URL in a transport error. Some
+// vendors authenticate with query parameters (an access token, or an OAuth
+// client secret), which would otherwise be shown with the error.
+func redactURL(err error) error {
+	uerr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	u, perr := url.Parse(uerr.URL)
+	if perr != nil || u.RawQuery == "" {
+		return err
+	}
+	u.RawQuery = "REDACTED"
+	return &url.Error{Op: uerr.Op, URL: u.String(), Err: uerr.Err}
+}
+
+// embeddingOrder returns the input each returned vector belongs to: its
+// index field when the indices name each input exactly once, as
+// zhipu.Client checks, and otherwise its position in the response
+func embeddingOrder(indices []int) []int {
+	order := make([]int, len(indices))
+	seen := make([]bool, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= len(seen) || seen[index] {
+			for i := range order {
+				order[i] = i
+			}
+			return order
+		}
+		seen[index] = true
+		order[i] = index
+	}
+	return order
+}
+
+// isJSON reports whether resp carries a JSON document rather than an
+// event stream, which is how some vendors report errors of stream requests
+func isJSON(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json")
+}
+
+// vendorError is an APIError with a code zhipu does not know, tagged with
+// the category the vendor documents for it. errors.Is matches only that
+// category, and errors.As still finds the APIError.
+type vendorError struct {
+	*zhipu.APIError
+	category error
+}
+
+func (e *vendorError) Is(target error) bool {
+	return target == e.category
+}
+
+func (e *vendorError) As(target interface{}) bool {
+	if t, ok := target.(**zhipu.APIError); ok {
+		*t = e.APIError
+		return true
+	}
+	return false
+}
+
+// categorize tags apiErr with the category codes maps its code to
+func categorize(apiErr *zhipu.APIError, codes map[string]error) error {
+	if category, ok := codes[apiErr.Code]; ok {
+		return &vendorError{APIError: apiErr, category: category}
+	}
+	return apiErr
+}
+
+// errNotSupported reports an operation a vendor's API does not offer
+func errNotSupported(provider, what string) error {
+	return fmt.Errorf("%s does not support %s: %w", provider, what, errors.ErrUnsupported)
+}
+
+// chatResponse builds a one-choice reply from a vendor's response fields
+func chatResponse(id, model string, msg zhipu.Message, finishReason string, usage zhipu.Usage) *zhipu.ChatResponse {
+	out := &zhipu.ChatResponse{ID: id, Model: model, Usage: usage}
+	out.Choices = make([]struct {
+		Index        int           `json:"index"`
+		Message      zhipu.Message `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	}, 1)
+	out.Choices[0].Message = msg
+	out.Choices[0].FinishReason = finishReason
+	return out
+}
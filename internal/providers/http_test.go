@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// fastRetryPolicy retries like the default policy, without the long waits
+func fastRetryPolicy() zhipu.RetryPolicy {
+	return zhipu.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+// fixture reads a recorded vendor response from testdata
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// serveFixture answers with the recorded response name; .txt fixtures
+// are event streams and .json ones JSON documents
+func serveFixture(t *testing.T, w http.ResponseWriter, name string) {
+	t.Helper()
+	if filepath.Ext(name) == ".txt" {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(fixture(t, name))
+}
+
+func TestCategorize(t *testing.T) {
+	codes := map[string]error{"Throttling": zhipu.ErrRateLimited}
+
+	err := categorize(&zhipu.APIError{StatusCode: 500, Code: "Throttling"}, codes)
+	if !errors.Is(err, zhipu.ErrRateLimited) {
+		t.Errorf("errors.Is(%v, ErrRateLimited) = false", err)
+	}
+	if errors.Is(err, zhipu.ErrServer) {
+		t.Error("a categorized error also matches the status category")
+	}
+	var apiErr *zhipu.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "Throttling" {
+		t.Errorf("errors.As found %+v", apiErr)
+	}
+
+	err = categorize(&zhipu.APIError{StatusCode: 500, Code: "Other"}, codes)
+	if !errors.Is(err, zhipu.ErrServer) {
+		t.Errorf("unknown code lost the status category: %v", err)
+	}
+}
+
+func TestEmbeddingOrder(t *testing.T) {
+	tests := []struct {
+		indices []int
+		want    []int
+	}{
+		{[]int{0, 1, 2}, []int{0, 1, 2}},
+		{[]int{2, 0, 1}, []int{2, 0, 1}},
+		{[]int{0, 0, 0}, []int{0, 1, 2}}, // Missing indices decode as 0
+		{[]int{1, 1, 0}, []int{0, 1, 2}},
+		{[]int{0, 3, 1}, []int{0, 1, 2}},
+		{[]int{-1, 0}, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		if got := embeddingOrder(tt.indices); !slices.Equal(got, tt.want) {
+			t.Errorf("embeddingOrder(%v) = %v, want %v", tt.indices, got, tt.want)
+		}
+	}
+}
+
+func TestErrNotSupported(t *testing.T) {
+	if err := errNotSupported("spark", "embeddings"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("errNotSupported does not wrap errors.ErrUnsupported: %v", err)
+	}
+}
@@ -0,0 +1,513 @@
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// HunyuanBaseURL is the Tencent Cloud API endpoint of Hunyuan
+const HunyuanBaseURL = "https://hunyuan.tencentcloudapi.com"
+
+const (
+	hunyuanService = "hunyuan"
+	hunyuanVersion = "2023-09-01"
+)
+
+// hunyuanCodes maps Tencent Cloud error codes to the zhipu categories
+var hunyuanCodes = map[string]error{
+	"AuthFailure.SecretIdNotFound":                  zhipu.ErrAuth,
+	"AuthFailure.SignatureExpire":                   zhipu.ErrAuth,
+	"AuthFailure.SignatureFailure":                  zhipu.ErrAuth,
+	"AuthFailure.InvalidSecretId":                   zhipu.ErrAuth,
+	"AuthFailure.UnauthorizedOperation":             zhipu.ErrAuth,
+	"RequestLimitExceeded":                          zhipu.ErrRateLimited,
+	"LimitExceeded":                                 zhipu.ErrRateLimited,
+	"FailedOperation.EngineRequestTimeout":          zhipu.ErrServer,
+	"FailedOperation.EngineServerError":             zhipu.ErrServer,
+	"FailedOperation.EngineServerLimitExceeded":     zhipu.ErrRateLimited,
+	"InternalError":                                 zhipu.ErrServer,
+	"FailedOperation.FreeResourcePackExhausted":     zhipu.ErrQuotaExceeded,
+	"FailedOperation.ResourcePackExhausted":         zhipu.ErrQuotaExceeded,
+	"FailedOperation.ServiceNotActivated":           zhipu.ErrQuotaExceeded,
+	"FailedOperation.ServiceStop":                   zhipu.ErrQuotaExceeded,
+	"FailedOperation.ServiceStopArrears":            zhipu.ErrQuotaExceeded,
+	"ResourceInsufficient.ChargeResourceExhaust":    zhipu.ErrQuotaExceeded,
+	"OperationDenied.ContentAuditFailed":            zhipu.ErrContentFiltered,
+	"InvalidParameterValue.ParameterValueError":     zhipu.ErrInvalidRequest,
+	"InvalidParameterValue.Model":                   zhipu.ErrInvalidRequest,
+	"FailedOperation.ContentLengthExceedsThreshold": zhipu.ErrContextLength,
+}
+
+func init() {
+	Register("hunyuan", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("TENCENTCLOUD_SECRET_ID")
+		}
+		if ps.SecretKey == "" {
+			ps.SecretKey = os.Getenv("TENCENTCLOUD_SECRET_KEY")
+		}
+		if ps.APIKey == "" || ps.SecretKey == "" {
+			return nil, fmt.Errorf("missing Tencent Cloud credentials. Set TENCENTCLOUD_SECRET_ID and TENCENTCLOUD_SECRET_KEY")
+		}
+		p := NewHunyuan(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// Hunyuan is the Provider for Tencent's Hunyuan models. Every request is
+// a Tencent Cloud API action signed with TC3-HMAC-SHA256, and errors come
+// back with HTTP 200 inside the response envelope.
+type Hunyuan struct {
+	httpBackend
+	secretID  string
+	secretKey string
+	region    string // Optional; Hunyuan serves every region from one endpoint
+
+	now func() time.Time // Signing clock, replaced in tests
+}
+
+// NewHunyuan creates a provider called name for the account in ps, whose
+// APIKey is the SecretId and SecretKey the SecretKey
+func NewHunyuan(name string, ps config.ProviderSettings) *Hunyuan {
+	return &Hunyuan{
+		httpBackend: newHTTPBackend(name, ps, HunyuanBaseURL),
+		secretID:    ps.APIKey,
+		secretKey:   ps.SecretKey,
+		region:      ps.Region,
+		now:         time.Now,
+	}
+}
+
+type hunyuanRequest struct {
+	Model       string           `json:"Model"`
+	Messages    []hunyuanMessage `json:"Messages"`
+	Stream      bool             `json:"Stream"`
+	Temperature *float64         `json:"Temperature,omitempty"`
+	TopP        *float64         `json:"TopP,omitempty"`
+	Stop        []string         `json:"Stop,omitempty"`
+	Seed        *int             `json:"Seed,omitempty"`
+	Tools       []hunyuanTool    `json:"Tools,omitempty"`
+	ToolChoice  string           `json:"ToolChoice,omitempty"` // "none", "auto" or "custom"
+	CustomTool  *hunyuanTool     `json:"CustomTool,omitempty"`
+}
+
+type hunyuanMessage struct {
+	Role       string            `json:"Role"`
+	Content    string            `json:"Content,omitempty"`
+	Contents   []hunyuanContent  `json:"Contents,omitempty"` // Text and images, instead of Content
+	ToolCallID string            `json:"ToolCallId,omitempty"`
+	ToolCalls  []hunyuanToolCall `json:"ToolCalls,omitempty"`
+
+	ReasoningContent string `json:"ReasoningContent,omitempty"` // Replies only
+}
+
+type hunyuanContent struct {
+	Type     string           `json:"Type"` // "text" or "image_url"
+	Text     string           `json:"Text,omitempty"`
+	ImageURL *hunyuanImageURL `json:"ImageUrl,omitempty"`
+}
+
+type hunyuanImageURL struct {
+	URL string `json:"Url"`
+}
+
+// hunyuanTool sends the parameter schema as a JSON string
+type hunyuanTool struct {
+	Type     string `json:"Type"`
+	Function struct {
+		Name        string `json:"Name"`
+		Description string `json:"Description,omitempty"`
+		Parameters  string `json:"Parameters"`
+	} `json:"Function"`
+}
+
+type hunyuanToolCall struct {
+	Index    int    `json:"Index"`
+	ID       string `json:"Id"`
+	Type     string `json:"Type"`
+	Function struct {
+		Name      string `json:"Name"`
+		Arguments string `json:"Arguments"`
+	} `json:"Function"`
+}
+
+type hunyuanError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// hunyuanReply is a whole reply, or one chunk of a streamed one
+type hunyuanReply struct {
+	ID        string `json:"Id"`
+	RequestID string `json:"RequestId"`
+	Choices   []struct {
+		FinishReason string         `json:"FinishReason"`
+		Message      hunyuanMessage `json:"Message"`
+		Delta        hunyuanMessage `json:"Delta"`
+	} `json:"Choices"`
+	Usage struct {
+		PromptTokens     int `json:"PromptTokens"`
+		CompletionTokens int `json:"CompletionTokens"`
+		TotalTokens      int `json:"TotalTokens"`
+	} `json:"Usage"`
+	Error    *hunyuanError `json:"Error"`
+	ErrorMsg *struct {
+		Code int    `json:"Code"`
+		Msg  string `json:"Msg"`
+	} `json:"ErrorMsg"` // In-stream errors
+}
+
+// toHunyuanRequest translates req. Messages with images use Contents,
+// tool schemas are sent as strings and a forced tool becomes CustomTool.
+func toHunyuanRequest(req *zhipu.ChatRequest) (*hunyuanRequest, error) {
+	out := &hunyuanRequest{
+		Model:       req.Model,
+		Messages:    make([]hunyuanMessage, 0, len(req.Messages)),
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+	}
+	for _, msg := range req.Messages {
+		hm := hunyuanMessage{Role: msg.Role, Content: msg.Text(), ToolCallID: msg.ToolCallID}
+		if urls := msg.ImageURLs(); len(urls) > 0 {
+			hm.Content = ""
+			hm.Contents = append(hm.Contents, hunyuanContent{Type: "text", Text: msg.Text()})
+			for _, url := range urls {
+				hm.Contents = append(hm.Contents, hunyuanContent{Type: "image_url", ImageURL: &hunyuanImageURL{URL: url}})
+			}
+		}
+		for i, tc := range msg.ToolCalls {
+			call := hunyuanToolCall{Index: i, ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = tc.Function.Arguments
+			hm.ToolCalls = append(hm.ToolCalls, call)
+		}
+		out.Messages = append(out.Messages, hm)
+	}
+
+	tools := make(map[string]hunyuanTool)
+	for _, tool := range req.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			continue
+		}
+		params, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("marshal parameters of %s: %w", tool.Function.Name, err)
+		}
+		ht := hunyuanTool{Type: "function"}
+		ht.Function.Name = tool.Function.Name
+		ht.Function.Description = tool.Function.Description
+		ht.Function.Parameters = string(params)
+		out.Tools = append(out.Tools, ht)
+		tools[ht.Function.Name] = ht
+	}
+	if choice := req.ToolChoice; choice != nil && len(out.Tools) > 0 {
+		switch {
+		case choice.Function != "":
+			if tool, ok := tools[choice.Function]; ok {
+				out.ToolChoice = "custom"
+				out.CustomTool = &tool
+			}
+		case choice.Mode == zhipu.ToolChoiceModeNone:
+			out.ToolChoice = "none"
+		default:
+			out.ToolChoice = "auto" // Hunyuan cannot require a call
+		}
+	}
+	if req.DoSample != nil && !*req.DoSample && req.Temperature == nil {
+		out.Temperature = zhipu.Float64(0)
+	}
+	return out, nil
+}
+
+func (m hunyuanMessage) toolCalls() []zhipu.ToolCall {
+	var calls []zhipu.ToolCall
+	for _, tc := range m.ToolCalls {
+		calls = append(calls, tc.toolCall())
+	}
+	return calls
+}
+
+func (c hunyuanToolCall) toolCall() zhipu.ToolCall {
+	call := zhipu.ToolCall{ID: c.ID, Type: "function"}
+	call.Function.Name = c.Function.Name
+	call.Function.Arguments = c.Function.Arguments
+	return call
+}
+
+func (r *hunyuanReply) usage() *zhipu.Usage {
+	return &zhipu.Usage{
+		PromptTokens:     r.Usage.PromptTokens,
+		CompletionTokens: r.Usage.CompletionTokens,
+		TotalTokens:      r.Usage.TotalTokens,
+	}
+}
+
+// err returns the error the envelope reports, or nil
+func (r *hunyuanReply) err() error {
+	switch {
+	case r.Error != nil && r.Error.Code != "":
+		return categorize(&zhipu.APIError{
+			StatusCode: http.StatusOK,
+			Code:       r.Error.Code,
+			Message:    r.Error.Message,
+			RequestID:  r.RequestID,
+		}, hunyuanCodes)
+	case r.ErrorMsg != nil && r.ErrorMsg.Code != 0:
+		return &zhipu.APIError{
+			StatusCode: http.StatusOK,
+			Code:       strconv.Itoa(r.ErrorMsg.Code),
+			Message:    r.ErrorMsg.Msg,
+			RequestID:  r.RequestID,
+		}
+	}
+	return nil
+}
+
+// Chat sends a chat request and waits for the whole reply
+func (p *Hunyuan) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire, err := toHunyuanRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	wire.Stream = false
+	var reply hunyuanReply
+	if err := p.call(ctx, "ChatCompletions", wire, &reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Choices) == 0 {
+		return nil, fmt.Errorf("%s: response has no choices", p.name)
+	}
+	choice := reply.Choices[0]
+	out := chatResponse(reply.ID, req.Model, zhipu.Message{
+		Role:             "assistant",
+		Content:          choice.Message.Content,
+		ToolCalls:        choice.Message.toolCalls(),
+		ReasoningContent: choice.Message.ReasoningContent,
+	}, choice.FinishReason, *reply.usage())
+	out.RequestID = reply.RequestID
+	return out, nil
+}
+
+// ChatStream streams a chat reply. Errors found before the stream starts
+// arrive as a JSON envelope instead of events.
+func (p *Hunyuan) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	wire, err := toHunyuanRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(ctx, "ChatCompletions", wire)
+	if err != nil {
+		return nil, err
+	}
+	if isJSON(resp) {
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		var reply hunyuanReply
+		if err := decodeHunyuanResponse(data, &reply); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: expected an event stream", p.name)
+	}
+	return zhipu.NewSSEStream(resp.Body, req.RequestID, decodeHunyuanChunk), nil
+}
+
+// decodeHunyuanChunk converts one streamed chunk to events. Usage is
+// cumulative, so it is only reported with the finish reason.
+func decodeHunyuanChunk(payload []byte) ([]zhipu.StreamEvent, error) {
+	var chunk hunyuanReply
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return nil, fmt.Errorf("decode stream: %w", err)
+	}
+	if err := chunk.err(); err != nil {
+		return nil, err
+	}
+
+	var events []zhipu.StreamEvent
+	for _, choice := range chunk.Choices {
+		if text := choice.Delta.ReasoningContent; text != "" {
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventReasoningDelta, Text: text})
+		}
+		if text := choice.Delta.Content; text != "" {
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventTextDelta, Text: text})
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			call := tc.toolCall()
+			events = append(events, zhipu.StreamEvent{Type: zhipu.EventToolCallDelta, ToolCallIndex: tc.Index, ToolCall: &call})
+		}
+		if choice.FinishReason != "" {
+			events = append(events,
+				zhipu.StreamEvent{Type: zhipu.EventFinish, FinishReason: choice.FinishReason},
+				zhipu.StreamEvent{Type: zhipu.EventUsage, Usage: chunk.usage()})
+		}
+	}
+	return events, nil
+}
+
+// Embeddings embeds req.Input with the GetEmbedding action. Hunyuan has a
+// single embedding model, so req.Model is ignored.
+func (p *Hunyuan) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var reply struct {
+		Data []struct {
+			Index     int       `json:"Index"`
+			Embedding []float32 `json:"Embedding"`
+		} `json:"Data"`
+		Usage struct {
+			PromptTokens int `json:"PromptTokens"`
+			TotalTokens  int `json:"TotalTokens"`
+		} `json:"Usage"`
+	}
+	if err := p.call(ctx, "GetEmbedding", map[string][]string{"InputList": req.Input}, &reply); err != nil {
+		return nil, err
+	}
+	result := &zhipu.EmbeddingResponse{
+		Model:      "hunyuan-embedding",
+		Embeddings: make([][]float32, len(req.Input)),
+		Usage:      zhipu.Usage{PromptTokens: reply.Usage.PromptTokens, TotalTokens: reply.Usage.TotalTokens},
+	}
+	if len(reply.Data) != len(req.Input) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(reply.Data), len(req.Input))
+	}
+	indices := make([]int, len(reply.Data))
+	for i, d := range reply.Data {
+		indices[i] = d.Index
+	}
+	for i, pos := range embeddingOrder(indices) {
+		result.Embeddings[pos] = reply.Data[i].Embedding
+	}
+	return result, nil
+}
+
+// Models lists the Hunyuan models in the catalogue
+func (p *Hunyuan) Models(ctx context.Context) ([]ModelInfo, error) {
+	return Catalogue("hunyuan"), nil
+}
+
+// call runs action and decodes the "Response" envelope into out
+func (p *Hunyuan) call(ctx context.Context, action string, body, out interface{}) error {
+	resp, err := p.do(ctx, action, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	return decodeHunyuanResponse(data, out)
+}
+
+// decodeHunyuanResponse decodes the "Response" envelope of data into out,
+// or returns the error it reports
+func decodeHunyuanResponse(data []byte, out interface{}) error {
+	var env struct{ Response json.RawMessage }
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	var status hunyuanReply
+	if err := json.Unmarshal(env.Response, &status); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if err := status.err(); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(env.Response, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// do posts a signed action. On success the caller must close resp.Body.
+func (p *Hunyuan) do(ctx context.Context, action string, body interface{}) (*http.Response, error) {
+	httpReq, payload, err := p.newRequest(ctx, http.MethodPost, "/", body)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := p.now().Unix()
+	httpReq.Header.Set("X-TC-Action", action)
+	httpReq.Header.Set("X-TC-Version", hunyuanVersion)
+	httpReq.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	if p.region != "" {
+		httpReq.Header.Set("X-TC-Region", p.region)
+	}
+	httpReq.Header.Set("Authorization", tc3Authorization(p.secretID, p.secretKey, httpReq.URL.Host, action, payload, timestamp))
+	return p.send(httpReq, nil)
+}
+
+// tc3SignedHeaders are the headers golem signs in each request
+const tc3SignedHeaders = "content-type;host;x-tc-action"
+
+// tc3Authorization signs a JSON POST to a Tencent Cloud API with
+// TC3-HMAC-SHA256 and returns the Authorization header
+func tc3Authorization(secretID, secretKey, host, action string, payload []byte, timestamp int64) string {
+	canonicalRequest := tc3CanonicalRequest("application/json", host, action, payload)
+	return tc3Sign(secretID, secretKey, hunyuanService, tc3SignedHeaders, canonicalRequest, timestamp)
+}
+
+// tc3CanonicalRequest is the canonical form of a POST of payload to "/",
+// over tc3SignedHeaders
+func tc3CanonicalRequest(contentType, host, action string, payload []byte) string {
+	return strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:" + contentType + "\nhost:" + host + "\nx-tc-action:" + strings.ToLower(action) + "\n",
+		tc3SignedHeaders,
+		sha256Hex(payload),
+	}, "\n")
+}
+
+// tc3Sign signs canonicalRequest for service with a key derived from the
+// UTC date of timestamp, and returns the Authorization header
+func tc3Sign(secretID, secretKey, service, signedHeaders, canonicalRequest string, timestamp int64) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	scope := date + "/" + service + "/tc3_request"
+	stringToSign := "TC3-HMAC-SHA256\n" + strconv.FormatInt(timestamp, 10) + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("TC3"+secretKey), date)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		secretID, scope, signedHeaders, signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newHunyuanTest(t *testing.T, handler http.HandlerFunc) *Hunyuan {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	p := NewHunyuan("hunyuan", config.ProviderSettings{BaseURL: server.URL, APIKey: "AKIDtest", SecretKey: "secret", Region: "ap-guangzhou"})
+	p.now = func() time.Time { return time.Unix(1736300000, 0) }
+	return p
+}
+
+// checkSignature verifies that r is signed over the headers and body it
+// carries. TestTC3Sign checks the signing itself.
+func checkSignature(t *testing.T, r *http.Request, body []byte) {
+	t.Helper()
+	action := r.Header.Get("X-TC-Action")
+	if r.Header.Get("X-TC-Timestamp") != "1736300000" || r.Header.Get("X-TC-Version") != hunyuanVersion || r.Header.Get("X-TC-Region") != "ap-guangzhou" {
+		t.Errorf("headers = %v", r.Header)
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "TC3-HMAC-SHA256 Credential=AKIDtest/2025-01-08/hunyuan/tc3_request, SignedHeaders=content-type;host;x-tc-action, Signature=") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.Path,
+		r.URL.RawQuery,
+		"content-type:" + r.Header.Get("Content-Type") + "\nhost:" + r.Host + "\nx-tc-action:" + strings.ToLower(action) + "\n",
+		"content-type;host;x-tc-action",
+		sha256Hex(body),
+	}, "\n")
+	if want := tc3Sign("AKIDtest", "secret", "hunyuan", "content-type;host;x-tc-action", canonicalRequest, 1736300000); auth != want {
+		t.Errorf("Authorization = %q, want %q", auth, want)
+	}
+}
+
+// The example of Tencent Cloud's API 3.0 signature v3 documentation: a CVM
+// DescribeInstances call on 2019-02-25, with the credentials masked as
+// published
+const (
+	tc3ExampleSecretID  = "AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******"
+	tc3ExampleSecretKey = "Gu5t9xGARNpq86cd98joQYCN3*******"
+	tc3ExampleTimestamp = 1551113065
+	tc3ExamplePayload   = `{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`
+)
+
+func TestTC3CanonicalRequest(t *testing.T) {
+	canonicalRequest := tc3CanonicalRequest("application/json; charset=utf-8", "cvm.tencentcloudapi.com", "DescribeInstances", []byte(tc3ExamplePayload))
+	if !strings.HasSuffix(canonicalRequest, "\n35e9c5b0e3ae67532d3c9f17ead6c90222632e5b1ff7f6e89887f1398934f064") {
+		t.Errorf("payload hash of %q differs from the example", canonicalRequest)
+	}
+	if got, want := sha256Hex([]byte(canonicalRequest)), "7019a55be8395899b900fb5564e4200d984910f34794a27cb3fb7d10ff6a1e84"; got != want {
+		t.Errorf("hashed canonical request = %s, want %s", got, want)
+	}
+}
+
+func TestTC3Sign(t *testing.T) {
+	// The example signs content-type and host only
+	canonicalRequest := "POST\n/\n\ncontent-type:application/json; charset=utf-8\nhost:cvm.tencentcloudapi.com\n\ncontent-type;host\n" + sha256Hex([]byte(tc3ExamplePayload))
+	got := tc3Sign(tc3ExampleSecretID, tc3ExampleSecretKey, "cvm", "content-type;host", canonicalRequest, tc3ExampleTimestamp)
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******/2019-02-25/cvm/tc3_request, SignedHeaders=content-type;host, " +
+		"Signature=2230eefd229f582d8b1b891af7107b91597240707d778ab3738f756258d7652c"
+	if got != want {
+		t.Errorf("tc3Sign() = %q\nwant %q", got, want)
+	}
+}
+
+func TestTC3Authorization(t *testing.T) {
+	// Signed with the example's credentials and time outside golem
+	payload := []byte(`{"Model":"hunyuan-lite","Messages":[{"Role":"user","Content":"hi"}]}`)
+	got := tc3Authorization(tc3ExampleSecretID, tc3ExampleSecretKey, "hunyuan.tencentcloudapi.com", "ChatCompletions", payload, tc3ExampleTimestamp)
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******/2019-02-25/hunyuan/tc3_request, SignedHeaders=content-type;host;x-tc-action, " +
+		"Signature=21bbb6556a547473c9a8daa0a10fa9560a9fa06b0fd9dd3a29cf0375908b1ec2"
+	if got != want {
+		t.Errorf("tc3Authorization() = %q\nwant %q", got, want)
+	}
+}
+
+func TestHunyuan_ChatStream(t *testing.T) {
+	var got hunyuanRequest
+	p := newHunyuanTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		checkSignature(t, r, body)
+		if action := r.Header.Get("X-TC-Action"); action != "ChatCompletions" {
+			t.Errorf("X-TC-Action = %q", action)
+		}
+		json.Unmarshal(body, &got)
+		serveFixture(t, w, "hunyuan_stream.txt")
+	})
+
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model: "hunyuan-functioncall",
+		Messages: []zhipu.Message{{Role: "user", Content: []zhipu.ContentPart{
+			{Type: "text", Text: "read go.mod"},
+			{Type: "image_url", ImageURL: &zhipu.ImageURL{URL: "https://example.com/a.png"}},
+		}}},
+		Tools: []zhipu.Tool{zhipu.NewFunctionTool("read_file", "Read a file",
+			zhipu.NewObjectSchema(map[string]*zhipu.JSONSchema{"path": zhipu.StringProp("File path")}, []string{"path"}))},
+		ToolChoice: zhipu.ForceTool("read_file"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	if !got.Stream || got.ToolChoice != "custom" || got.CustomTool == nil || got.CustomTool.Function.Name != "read_file" {
+		t.Errorf("request = %+v", got)
+	}
+	if params := got.Tools[0].Function.Parameters; !strings.HasPrefix(params, `{"type":"object"`) {
+		t.Errorf("Parameters = %q, want the schema as a JSON string", params)
+	}
+	if msg := got.Messages[0]; msg.Content != "" || len(msg.Contents) != 2 || msg.Contents[1].ImageURL.URL != "https://example.com/a.png" {
+		t.Errorf("message = %+v", msg)
+	}
+	if result.Content != "Reading go.mod." {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_cu1" || result.ToolCalls[0].Function.Arguments != `{"path":"go.mod"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 46 {
+		t.Errorf("Usage = %+v, want the final cumulative counts", result.Usage)
+	}
+}
+
+func TestHunyuan_Chat(t *testing.T) {
+	p := newHunyuanTest(t, func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "hunyuan_chat.json")
+	})
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model:    "hunyuan-turbo",
+		Messages: []zhipu.Message{{Role: "user", Content: "list files"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := resp.Choices[0].Message
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name != "list_dir" || resp.Choices[0].FinishReason != "tool_calls" {
+		t.Errorf("reply = %+v", resp.Choices[0])
+	}
+	if resp.RequestID != "hy-req-2" || resp.Usage.TotalTokens != 75 {
+		t.Errorf("RequestID = %q, Usage = %+v", resp.RequestID, resp.Usage)
+	}
+}
+
+func TestHunyuan_Errors(t *testing.T) {
+	p := newHunyuanTest(t, func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "hunyuan_error.json")
+	})
+	req := &zhipu.ChatRequest{Model: "hunyuan-lite"}
+
+	if _, err := p.Chat(context.Background(), req); !errors.Is(err, zhipu.ErrAuth) {
+		t.Errorf("Chat err = %v, want ErrAuth", err)
+	}
+	_, err := p.ChatStream(context.Background(), req)
+	if !errors.Is(err, zhipu.ErrAuth) {
+		t.Errorf("ChatStream err = %v, want ErrAuth", err)
+	}
+	var apiErr *zhipu.APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID == "" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestHunyuan_Embeddings(t *testing.T) {
+	p := newHunyuanTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if action := r.Header.Get("X-TC-Action"); action != "GetEmbedding" {
+			t.Errorf("X-TC-Action = %q", action)
+		}
+		w.Write([]byte(`{"Response":{"Data":[{"Embedding":[0.1],"Index":0},{"Embedding":[0.2],"Index":1}],"Usage":{"PromptTokens":2,"TotalTokens":2},"RequestId":"r"}}`))
+	})
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.2 {
+		t.Errorf("Embeddings = %v", resp.Embeddings)
+	}
+}
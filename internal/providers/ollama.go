@@ -0,0 +1,402 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// OllamaBaseURL is where a local Ollama server listens by default
+const OllamaBaseURL = "http://localhost:11434"
+
+func init() {
+	Register("ollama", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.BaseURL == "" {
+			ps.BaseURL = os.Getenv("OLLAMA_HOST")
+		}
+		if ps.BaseURL == "" {
+			ps.BaseURL = OllamaBaseURL
+		}
+		if !strings.Contains(ps.BaseURL, "://") {
+			ps.BaseURL = "http://" + ps.BaseURL // OLLAMA_HOST is often host:port
+		}
+		p := NewOllama(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// Ollama is the Provider for a local Ollama server, using its native
+// /api/chat and /api/embeddings endpoints. It needs no network access
+// beyond the server, so golem keeps working offline.
+type Ollama struct {
+	httpBackend
+	embeddingModel string
+}
+
+// NewOllama creates a provider called name for the server in ps
+func NewOllama(name string, ps config.ProviderSettings) *Ollama {
+	return &Ollama{
+		httpBackend:    newHTTPBackend(name, ps, OllamaBaseURL),
+		embeddingModel: ps.EmbeddingModel,
+	}
+}
+
+type ollamaRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Tools    []zhipu.Tool           `json:"tools,omitempty"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+	Stream   bool                   `json:"stream"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // Base64, no data URL prefix
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall carries arguments as a JSON object, not a string
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaResponse is a whole reply, or one line of a streamed one
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// toOllamaRequest translates req. Sampling parameters move to options,
+// images become base64 and tool results are tagged with the name of the
+// function that produced them, since Ollama has no tool call IDs.
+func toOllamaRequest(req *zhipu.ChatRequest) *ollamaRequest {
+	out := &ollamaRequest{
+		Model:    req.Model,
+		Messages: make([]ollamaMessage, 0, len(req.Messages)),
+		Stream:   req.Stream,
+		Options:  make(map[string]interface{}),
+	}
+
+	callNames := make(map[string]string)
+	for _, msg := range req.Messages {
+		om := ollamaMessage{Role: msg.Role, Content: msg.Text()}
+		for _, url := range msg.ImageURLs() {
+			if _, data, ok := strings.Cut(url, ";base64,"); ok {
+				om.Images = append(om.Images, data)
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			om.ToolCalls = append(om.ToolCalls, call)
+			callNames[tc.ID] = tc.Function.Name
+		}
+		if msg.Role == "tool" {
+			om.ToolName = callNames[msg.ToolCallID]
+		}
+		out.Messages = append(out.Messages, om)
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Type == "function" {
+			out.Tools = append(out.Tools, tool)
+		}
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == zhipu.ResponseFormatJSON {
+		out.Format = "json"
+	}
+
+	if req.Temperature != nil {
+		out.Options["temperature"] = *req.Temperature
+	} else if req.DoSample != nil && !*req.DoSample {
+		out.Options["temperature"] = 0
+	}
+	if req.TopP != nil {
+		out.Options["top_p"] = *req.TopP
+	}
+	if req.MaxTokens > 0 {
+		out.Options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		out.Options["stop"] = req.Stop
+	}
+	if req.Seed != nil {
+		out.Options["seed"] = *req.Seed
+	}
+	if len(out.Options) == 0 {
+		out.Options = nil
+	}
+	return out
+}
+
+// toolCalls converts Ollama's calls, numbering them for IDs
+func (m ollamaMessage) toolCalls() []zhipu.ToolCall {
+	calls := make([]zhipu.ToolCall, len(m.ToolCalls))
+	for i, tc := range m.ToolCalls {
+		calls[i].ID = fmt.Sprintf("call_%d", i)
+		calls[i].Type = "function"
+		calls[i].Function.Name = tc.Function.Name
+		calls[i].Function.Arguments = string(tc.Function.Arguments)
+	}
+	return calls
+}
+
+func (r *ollamaResponse) usage() *zhipu.Usage {
+	return &zhipu.Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+// finishReason reports "tool_calls" like the other providers when the
+// reply ends with calls
+func (r *ollamaResponse) finishReason() string {
+	if len(r.Message.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	if r.DoneReason == "" {
+		return "stop"
+	}
+	return r.DoneReason
+}
+
+// Chat sends a chat request and waits for the whole reply
+func (p *Ollama) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire := toOllamaRequest(req)
+	wire.Stream = false
+	resp, err := p.do(ctx, http.MethodPost, "/api/chat", wire)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var reply ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return chatResponse("", reply.Model, zhipu.Message{
+		Role:             "assistant",
+		Content:          reply.Message.Content,
+		ToolCalls:        reply.Message.toolCalls(),
+		ReasoningContent: reply.Message.Thinking,
+	}, reply.finishReason(), *reply.usage()), nil
+}
+
+// ChatStream streams a chat reply. Ollama sends tool calls whole, so they
+// arrive as EventToolCall without deltas.
+func (p *Ollama) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	resp, err := p.do(ctx, http.MethodPost, "/api/chat", toOllamaRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return zhipu.NewLineStream(resp.Body, req.RequestID, decodeOllamaLine), nil
+}
+
+// decodeOllamaLine converts one streamed /api/chat line to events
+func decodeOllamaLine(line []byte) ([]zhipu.StreamEvent, error) {
+	var chunk ollamaResponse
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return nil, fmt.Errorf("decode stream: %w", err)
+	}
+	if chunk.Error != "" {
+		return nil, &zhipu.APIError{StatusCode: http.StatusOK, Message: chunk.Error}
+	}
+
+	var events []zhipu.StreamEvent
+	if chunk.Message.Thinking != "" {
+		events = append(events, zhipu.StreamEvent{Type: zhipu.EventReasoningDelta, Text: chunk.Message.Thinking})
+	}
+	if chunk.Message.Content != "" {
+		events = append(events, zhipu.StreamEvent{Type: zhipu.EventTextDelta, Text: chunk.Message.Content})
+	}
+	for i, call := range chunk.Message.toolCalls() {
+		events = append(events, zhipu.StreamEvent{Type: zhipu.EventToolCall, ToolCallIndex: i, ToolCall: &call})
+	}
+	if chunk.Done {
+		events = append(events,
+			zhipu.StreamEvent{Type: zhipu.EventFinish, FinishReason: chunk.finishReason()},
+			zhipu.StreamEvent{Type: zhipu.EventUsage, Usage: chunk.usage()})
+	}
+	return events, nil
+}
+
+// Embeddings embeds each input with one /api/embeddings call, using
+// req.Model or the configured embedding model
+func (p *Ollama) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.embeddingModel
+	}
+	if model == "" {
+		return nil, fmt.Errorf("%s: no embedding model; set embedding_model in the provider settings, e.g. nomic-embed-text", p.name)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	result := &zhipu.EmbeddingResponse{Model: model, Embeddings: make([][]float32, len(req.Input))}
+	for i, input := range req.Input {
+		resp, err := p.do(ctx, http.MethodPost, "/api/embeddings", map[string]string{"model": model, "prompt": input})
+		if err != nil {
+			return nil, err
+		}
+		var wire struct {
+			Embedding []float32 `json:"embedding"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&wire)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		result.Embeddings[i] = wire.Embedding
+	}
+	return result, nil
+}
+
+// Models lists the models installed on the server
+func (p *Ollama) Models(ctx context.Context) ([]ModelInfo, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	resp, err := p.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name    string `json:"name"`
+			Size    int64  `json:"size"`
+			Details struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		desc := strings.Join(nonEmpty(m.Details.Family, m.Details.ParameterSize, m.Details.QuantizationLevel), " ")
+		if m.Size > 0 {
+			desc += fmt.Sprintf(" (%.1f GB)", float64(m.Size)/1e9)
+		}
+		models = append(models, ModelInfo{ID: m.Name, Name: m.Name, Description: strings.TrimSpace(desc), Provider: p.name})
+	}
+	return models, nil
+}
+
+// PullProgress is one status update of a model download
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+}
+
+// String describes the update, with a percentage while a layer downloads
+func (pp PullProgress) String() string {
+	if pp.Total > 0 {
+		return fmt.Sprintf("%s %d%% (%.0f/%.0f MB)", pp.Status, pp.Completed*100/pp.Total,
+			float64(pp.Completed)/1e6, float64(pp.Total)/1e6)
+	}
+	return pp.Status
+}
+
+// Pull downloads model to the server, calling progress with each status
+// update. It returns once the download has finished or failed.
+func (p *Ollama) Pull(ctx context.Context, model string, progress func(PullProgress)) error {
+	resp, err := p.do(ctx, http.MethodPost, "/api/pull", map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line struct {
+			PullProgress
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Error != "" {
+			return fmt.Errorf("pull %s: %s", model, line.Error)
+		}
+		if progress != nil {
+			progress(line.PullProgress)
+		}
+		if line.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pull %s: %w", model, err)
+	}
+	return fmt.Errorf("pull %s: stream ended before success", model)
+}
+
+// do sends a request to the server; failures become *zhipu.APIError. On
+// success the caller must close resp.Body.
+func (p *Ollama) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	httpReq, _, err := p.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return p.send(httpReq, parseOllamaError)
+}
+
+// parseOllamaError reads Ollama's {"error": "..."} bodies
+func parseOllamaError(status int, header http.Header, body []byte) error {
+	apiErr := &zhipu.APIError{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		apiErr.Message = payload.Error
+	}
+	return apiErr
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
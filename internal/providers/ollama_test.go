@@ -0,0 +1,251 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newOllamaTest(t *testing.T, handler http.HandlerFunc) *Ollama {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewOllama("ollama", config.ProviderSettings{BaseURL: server.URL})
+}
+
+func TestOllama_ChatStream(t *testing.T) {
+	var got ollamaRequest
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range []string{
+			`{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"Need the file."},"done":false}`,
+			`{"model":"qwen3","message":{"role":"assistant","content":"Reading it."},"done":false}`,
+			`{"model":"qwen3","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"go.mod"}}}]},"done":false}`,
+			`{"model":"qwen3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":9}`,
+		} {
+			io.WriteString(w, line+"\n")
+		}
+	})
+
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model:       "qwen3",
+		Messages:    []zhipu.Message{{Role: "user", Content: "read go.mod"}},
+		Temperature: zhipu.Float64(0.2),
+		MaxTokens:   256,
+		Tools:       []zhipu.Tool{zhipu.NewFunctionTool("read_file", "Read a file", zhipu.NewObjectSchema(nil, nil))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	if !got.Stream || got.Options["temperature"] != 0.2 || got.Options["num_predict"] != 256.0 {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Tools) != 1 {
+		t.Errorf("sent %d tools, want 1", len(got.Tools))
+	}
+	if result.Reasoning != "Need the file." || result.Content != "Reading it." {
+		t.Errorf("Reasoning = %q, Content = %q", result.Reasoning, result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Arguments != `{"path":"go.mod"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+	if result.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q", result.FinishReason)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 29 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestOllama_StreamError(t *testing.T) {
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n")
+		io.WriteString(w, `{"error":"model runner has unexpectedly stopped"}`+"\n")
+	})
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{Model: "llama3.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "unexpectedly stopped") {
+		t.Errorf("Error = %v", result.Error)
+	}
+	if result.Content != "Hel" {
+		t.Errorf("Content = %q", result.Content)
+	}
+}
+
+func TestOllama_Chat(t *testing.T) {
+	var got ollamaRequest
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, `{"model":"llava","message":{"role":"assistant","content":"A cat."},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":3}`)
+	})
+
+	call := zhipu.ToolCall{ID: "call_0", Type: "function"}
+	call.Function.Name = "read_file"
+	call.Function.Arguments = `{"path":"a.png"}`
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model: "llava",
+		Messages: []zhipu.Message{
+			{Role: "user", Content: []zhipu.ContentPart{
+				{Type: "text", Text: "what is this?"},
+				{Type: "image_url", ImageURL: &zhipu.ImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+			}},
+			{Role: "assistant", ToolCalls: []zhipu.ToolCall{call}},
+			{Role: "tool", ToolCallID: "call_0", Content: "binary"},
+		},
+		DoSample: zhipu.Bool(false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Stream {
+		t.Error("Chat sent stream: true")
+	}
+	if got.Options["temperature"] != 0.0 {
+		t.Errorf("temperature = %v, want 0 for greedy decoding", got.Options["temperature"])
+	}
+	if msg := got.Messages[0]; msg.Content != "what is this?" || len(msg.Images) != 1 || msg.Images[0] != "iVBORw0KGgo=" {
+		t.Errorf("user message = %+v", msg)
+	}
+	if args := string(got.Messages[1].ToolCalls[0].Function.Arguments); args != `{"path":"a.png"}` {
+		t.Errorf("tool call arguments = %s, want a JSON object", args)
+	}
+	if got.Messages[2].ToolName != "read_file" {
+		t.Errorf("tool result ToolName = %q", got.Messages[2].ToolName)
+	}
+
+	if text, _ := resp.Choices[0].Message.Content.(string); text != "A cat." {
+		t.Errorf("Content = %v", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage.TotalTokens != 8 {
+		t.Errorf("TotalTokens = %d, want 8", resp.Usage.TotalTokens)
+	}
+}
+
+func TestOllama_NotFound(t *testing.T) {
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"model \"nope\" not found, try pulling it first"}`)
+	})
+	_, err := p.Chat(context.Background(), &zhipu.ChatRequest{Model: "nope"})
+	var apiErr *zhipu.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "try pulling it first") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOllama_Models(t *testing.T) {
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"models":[{"name":"llama3.2:latest","size":2019393189,
+			"details":{"family":"llama","parameter_size":"3.2B","quantization_level":"Q4_K_M"}}]}`)
+	})
+	models, err := p.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].ID != "llama3.2:latest" || models[0].Description != "llama 3.2B Q4_K_M (2.0 GB)" {
+		t.Errorf("Models() = %+v", models)
+	}
+}
+
+func TestOllama_Embeddings(t *testing.T) {
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model, Prompt string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" {
+			t.Errorf("model = %q", req.Model)
+		}
+		io.WriteString(w, `{"embedding":[`+map[string]string{"a": "0.1", "b": "0.2"}[req.Prompt]+`]}`)
+	})
+	p.embeddingModel = "nomic-embed-text"
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.2 {
+		t.Errorf("Embeddings = %v", resp.Embeddings)
+	}
+}
+
+func TestOllama_Pull(t *testing.T) {
+	p := newOllamaTest(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] == "missing" {
+			io.WriteString(w, `{"status":"pulling manifest"}`+"\n"+`{"error":"pull model manifest: file does not exist"}`+"\n")
+			return
+		}
+		for _, line := range []string{
+			`{"status":"pulling manifest"}`,
+			`{"status":"pulling 74701a8c35f6","digest":"sha256:74701a8c35f6","total":1000,"completed":250}`,
+			`{"status":"pulling 74701a8c35f6","digest":"sha256:74701a8c35f6","total":1000,"completed":1000}`,
+			`{"status":"success"}`,
+		} {
+			io.WriteString(w, line+"\n")
+		}
+	})
+
+	var updates []string
+	if err := p.Pull(context.Background(), "llama3.2", func(pp PullProgress) {
+		updates = append(updates, pp.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 4 || !strings.HasPrefix(updates[1], "pulling 74701a8c35f6 25%") {
+		t.Errorf("updates = %q", updates)
+	}
+
+	if err := p.Pull(context.Background(), "missing", nil); err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestForModel(t *testing.T) {
+	settings := config.Settings{APIKey: "k"}
+	def, err := New(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		model, provider, name string
+	}{
+		{"glm-4-32b-0414", "zai", "glm-4-32b-0414"},
+		{"ollama/llama3.2", "ollama", "llama3.2"},
+		{"zai/glm-z1-32b-0414", "zai", "glm-z1-32b-0414"},
+		{"Qwen/Qwen2.5-7B-Instruct", "zai", "Qwen/Qwen2.5-7B-Instruct"},
+	}
+	for _, tt := range tests {
+		p, name, err := ForModel(settings, def, tt.model)
+		if err != nil {
+			t.Errorf("ForModel(%q): %v", tt.model, err)
+			continue
+		}
+		if p.Name() != tt.provider || name != tt.name {
+			t.Errorf("ForModel(%q) = %s, %q; want %s, %q", tt.model, p.Name(), name, tt.provider, tt.name)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// OpenAIBaseURL is used by the "openai" provider when no base URL is set
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+func init() {
+	Register("openai", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.BaseURL == "" {
+			ps.BaseURL = os.Getenv("OPENAI_BASE_URL")
+		}
+		if ps.BaseURL == "" {
+			ps.BaseURL = OpenAIBaseURL
+		}
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		p := NewOpenAI(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// OpenAI is the Provider for OpenAI-compatible /v1 endpoints: OpenAI
+// itself, vLLM, llama.cpp server, LM Studio or an internal gateway.
+// Messages, tools and stream chunks share the Z.AI wire format, so the
+// zhipu types are sent as they are; only Z.AI extensions are left out.
+type OpenAI struct {
+	httpBackend
+	apiKey         string // Optional; local servers usually need none
+	embeddingModel string
+}
+
+// NewOpenAI creates a provider called name for the endpoint in ps
+func NewOpenAI(name string, ps config.ProviderSettings) *OpenAI {
+	return &OpenAI{
+		httpBackend:    newHTTPBackend(name, ps, OpenAIBaseURL),
+		apiKey:         ps.APIKey,
+		embeddingModel: ps.EmbeddingModel,
+	}
+}
+
+// openAIRequest is a chat completion request without the Z.AI extensions
+type openAIRequest struct {
+	Model             string                `json:"model"`
+	Messages          []zhipu.Message       `json:"messages"`
+	Temperature       *float64              `json:"temperature,omitempty"`
+	TopP              *float64              `json:"top_p,omitempty"`
+	MaxTokens         int                   `json:"max_tokens,omitempty"`
+	Stop              []string              `json:"stop,omitempty"`
+	N                 *int                  `json:"n,omitempty"`
+	Seed              *int                  `json:"seed,omitempty"`
+	Stream            bool                  `json:"stream,omitempty"`
+	StreamOptions     *openAIStreamOptions  `json:"stream_options,omitempty"`
+	Tools             []zhipu.Tool          `json:"tools,omitempty"`
+	ToolChoice        *zhipu.ToolChoice     `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool                 `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat    *zhipu.ResponseFormat `json:"response_format,omitempty"`
+	User              string                `json:"user,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// toOpenAIRequest translates req. Only function tools are kept, since web
+// search and retrieval are Z.AI services, and greedy decoding becomes
+// temperature 0.
+func toOpenAIRequest(req *zhipu.ChatRequest) *openAIRequest {
+	out := &openAIRequest{
+		Model:             req.Model,
+		Messages:          make([]zhipu.Message, len(req.Messages)),
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		MaxTokens:         req.MaxTokens,
+		Stop:              req.Stop,
+		N:                 req.N,
+		Seed:              req.Seed,
+		Stream:            req.Stream,
+		ToolChoice:        req.ToolChoice,
+		ParallelToolCalls: req.ParallelToolCalls,
+		ResponseFormat:    req.ResponseFormat,
+		User:              req.UserID,
+	}
+	for i, msg := range req.Messages {
+		msg.ReasoningContent = ""
+		out.Messages[i] = msg
+	}
+	for _, tool := range req.Tools {
+		if tool.Type == "function" {
+			out.Tools = append(out.Tools, tool)
+		}
+	}
+	if len(out.Tools) == 0 {
+		out.ToolChoice = nil
+		out.ParallelToolCalls = nil
+	}
+	if req.DoSample != nil && !*req.DoSample && req.Temperature == nil {
+		out.Temperature = zhipu.Float64(0)
+	}
+	if req.Stream {
+		out.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	return out
+}
+
+// Chat sends a chat completion
+func (p *OpenAI) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire := toOpenAIRequest(req)
+	wire.Stream = false
+	wire.StreamOptions = nil
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", wire, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp zhipu.ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &chatResp, nil
+}
+
+// ChatStream streams a chat completion
+func (p *OpenAI) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", toOpenAIRequest(req), "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return zhipu.NewStream(resp.Body, req.RequestID), nil
+}
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage zhipu.Usage `json:"usage"`
+}
+
+// Embeddings embeds req.Input with req.Model, or the configured embedding
+// model when it is empty
+func (p *OpenAI) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.embeddingModel
+	}
+	if model == "" {
+		return nil, fmt.Errorf("%s: no embedding model; set embedding_model in the provider settings", p.name)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	resp, err := p.do(ctx, http.MethodPost, "/embeddings", &openAIEmbeddingRequest{
+		Model:      model,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var wire openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&wire); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(wire.Data) != len(req.Input) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(wire.Data), len(req.Input))
+	}
+	result := &zhipu.EmbeddingResponse{
+		Model:      model,
+		Embeddings: make([][]float32, len(req.Input)),
+		Usage:      wire.Usage,
+	}
+	indices := make([]int, len(wire.Data))
+	for i, d := range wire.Data {
+		indices[i] = d.Index
+	}
+	for i, pos := range embeddingOrder(indices) {
+		result.Embeddings[pos] = wire.Data[i].Embedding
+	}
+	return result, nil
+}
+
+// Models lists the models the endpoint serves
+func (p *OpenAI) Models(ctx context.Context) ([]ModelInfo, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	resp, err := p.do(ctx, http.MethodGet, "/models", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{
+			ID:          m.ID,
+			Name:        m.ID,
+			Description: m.OwnedBy,
+			Context:     zhipu.ContextWindow(m.ID),
+			Provider:    p.name,
+		})
+	}
+	return models, nil
+}
+
+// do sends a request to the endpoint; failures become *zhipu.APIError, so
+// errors.Is works with the zhipu categories. On success the caller must
+// close resp.Body.
+func (p *OpenAI) do(ctx context.Context, method, path string, body interface{}, accept string) (*http.Response, error) {
+	httpReq, _, err := p.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	if accept != "" {
+		httpReq.Header.Set("Accept", accept)
+	}
+	return p.send(httpReq, nil)
+}
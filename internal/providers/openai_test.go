@@ -0,0 +1,260 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newOpenAITest(t *testing.T, handler http.HandlerFunc) *OpenAI {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	p := NewOpenAI("local", config.ProviderSettings{BaseURL: server.URL + "/v1/", APIKey: "sk-test"})
+	p.retry = fastRetryPolicy()
+	return p
+}
+
+func TestOpenAI_Chat(t *testing.T) {
+	var got map[string]interface{}
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, `{"id":"c1","model":"qwen2.5","choices":[{"index":0,"message":{"role":"assistant","content":null,
+			"tool_calls":[{"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"go.mod\"}"}}]},
+			"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}}`)
+	})
+
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model:     "qwen2.5",
+		Messages:  []zhipu.Message{{Role: "user", Content: "read go.mod"}, {Role: "assistant", Content: "ok", ReasoningContent: "hmm"}},
+		RequestID: "r1",
+		DoSample:  zhipu.Bool(false),
+		Tools: []zhipu.Tool{
+			zhipu.NewFunctionTool("read_file", "Read a file", zhipu.NewObjectSchema(nil, nil)),
+			zhipu.NewRetrievalTool("kb1", ""),
+		},
+		ToolChoice: zhipu.ToolChoiceAuto(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"request_id", "do_sample", "stream", "stream_options"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("request has %q", key)
+		}
+	}
+	if got["temperature"] != 0.0 {
+		t.Errorf("temperature = %v, want 0 for greedy decoding", got["temperature"])
+	}
+	if tools, _ := got["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("sent %d tools, want only the function tool", len(tools))
+	}
+	if msgs, _ := got["messages"].([]interface{}); len(msgs) == 2 {
+		if _, ok := msgs[1].(map[string]interface{})["reasoning_content"]; ok {
+			t.Error("reasoning_content was sent back")
+		}
+	}
+
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "read_file" {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if resp.Usage.TotalTokens != 20 {
+		t.Errorf("TotalTokens = %d, want 20", resp.Usage.TotalTokens)
+	}
+}
+
+func TestOpenAI_ChatStream(t *testing.T) {
+	var got map[string]interface{}
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Let me look"}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":\"go.mod\"}"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`,
+		} {
+			io.WriteString(w, "data: "+chunk+"\n\n")
+		}
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model:    "qwen2.5",
+		Messages: []zhipu.Message{{Role: "user", Content: "read go.mod"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	if opts, _ := got["stream_options"].(map[string]interface{}); opts["include_usage"] != true {
+		t.Errorf("stream_options = %v, want include_usage", got["stream_options"])
+	}
+	if result.Content != "Let me look" {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Arguments != `{"path":"go.mod"}` {
+		t.Errorf("ToolCalls = %+v", result.ToolCalls)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 12 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestOpenAI_Errors(t *testing.T) {
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`)
+	})
+	_, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{Model: "m"})
+	if !errors.Is(err, zhipu.ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestOpenAI_Retry(t *testing.T) {
+	var calls int
+	var bodies []string
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `{"error":{"message":"model is loading","type":"server_error"}}`)
+			return
+		}
+		io.WriteString(w, `{"id":"c1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	})
+
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{Model: "m", Messages: []zhipu.Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "ok" || calls != 2 {
+		t.Errorf("Content = %v after %d calls", resp.Choices[0].Message.Content, calls)
+	}
+	if bodies[0] == "" || bodies[1] != bodies[0] {
+		t.Errorf("retried with body %q, first was %q", bodies[1], bodies[0])
+	}
+}
+
+func TestOpenAI_StreamIdle(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-release // Then go quiet
+	})
+	p.streamIdle = 50 * time.Millisecond
+
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	for stream.Next() {
+	}
+	if !errors.Is(stream.Err(), zhipu.ErrStreamIdle) {
+		t.Errorf("err = %v, want ErrStreamIdle", stream.Err())
+	}
+}
+
+func TestOpenAI_Embeddings(t *testing.T) {
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		// Out of order on purpose
+		io.WriteString(w, `{"data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	})
+
+	if _, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a"}}); err == nil {
+		t.Error("expected an error without an embedding model")
+	}
+	p.embeddingModel = "nomic-embed-text"
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.3 {
+		t.Errorf("Embeddings = %v", resp.Embeddings)
+	}
+}
+
+func TestOpenAI_EmbeddingsBadIndices(t *testing.T) {
+	// Servers that leave out the index send 0 for every vector
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data":[{"embedding":[0.1]},{"embedding":[0.2]},{"embedding":[0.3]}]}`)
+	})
+	p.embeddingModel = "bge-m3"
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float32{0.1, 0.2, 0.3} {
+		if len(resp.Embeddings[i]) != 1 || resp.Embeddings[i][0] != want {
+			t.Errorf("Embeddings[%d] = %v, want [%v]", i, resp.Embeddings[i], want)
+		}
+	}
+}
+
+func TestOpenAI_Models(t *testing.T) {
+	p := newOpenAITest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		io.WriteString(w, `{"object":"list","data":[{"id":"llama-3.1-8b","object":"model","owned_by":"vllm"}]}`)
+	})
+	models, err := p.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].ID != "llama-3.1-8b" {
+		t.Errorf("Models() = %+v", models)
+	}
+}
+
+func TestNew_ProviderType(t *testing.T) {
+	p, err := New(config.Settings{
+		Provider: "vllm",
+		Providers: map[string]config.ProviderSettings{
+			"vllm": {Type: "openai", BaseURL: "http://localhost:8000/v1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "vllm" {
+		t.Errorf("Name() = %q, want vllm", p.Name())
+	}
+	if _, ok := ZhipuClient(p); ok {
+		t.Error("an OpenAI-compatible provider has no Z.AI client")
+	}
+}
@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// DefaultProvider is used when Settings.Provider is empty
+const DefaultProvider = "zai"
+
+// Provider is a chat backend. Requests, replies and stream events use the
+// zhipu types, which every implementation translates to and from its own
+// API, so the UI, CLI and agents work with any backend.
+type Provider interface {
+	// Name returns the registry name, e.g. "zai"
+	Name() string
+	// Chat sends a request and waits for the whole reply. Tools and
+	// ToolChoice are honoured where the backend supports function calling.
+	Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error)
+	// ChatStream streams the reply
+	ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error)
+	// Embeddings embeds req.Input, one vector per input
+	Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error)
+	// Models lists the chat models the backend offers
+	Models(ctx context.Context) ([]ModelInfo, error)
+}
+
+// Factory builds a provider from the settings
+type Factory func(settings config.Settings) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a provider available under name. It is meant to be called
+// from init and panics on duplicate names.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("providers: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Names returns the registered provider names, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the provider selected by settings.Provider. A name that is
+// not registered is looked up in settings.Providers and built by the
+// factory of its type, so several endpoints of one kind can be configured.
+func New(settings config.Settings) (Provider, error) {
+	if settings.Provider == "" {
+		settings.Provider = DefaultProvider
+	}
+	kind := settings.Provider
+	if typ := settings.ActiveProvider().Type; typ != "" {
+		kind = typ
+	}
+	registryMu.RLock()
+	factory, ok := registry[kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %v)", kind, Names())
+	}
+	return factory(settings)
+}
+
+// ForModel returns the provider to send model to, and the model name that
+// provider knows it by. A model written "<provider>/<name>", such as
+// "ollama/llama3.2", goes to that provider when it is registered or
+// configured; any other model goes to def. Model IDs that merely contain
+// a slash, like "Qwen/Qwen2.5-7B-Instruct", are left alone.
+func ForModel(settings config.Settings, def Provider, model string) (Provider, string, error) {
+	prefix, name, ok := strings.Cut(model, "/")
+	if !ok || !isProviderName(settings, prefix) {
+		return def, model, nil
+	}
+	if def != nil && def.Name() == prefix {
+		return def, name, nil
+	}
+	settings.Provider = prefix
+	p, err := New(settings)
+	return p, name, err
+}
+
+func isProviderName(settings config.Settings, name string) bool {
+	if _, ok := settings.Providers[name]; ok {
+		return true
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// ZhipuClient returns the Z.AI client behind p, for features only Z.AI
+// offers (knowledge bases, images, hosted agents, web search, batches).
+// Wrappers such as Router are looked through.
+func ZhipuClient(p Provider) (*zhipu.Client, bool) {
+	if w, ok := p.(interface{ Unwrap() Provider }); ok {
+		return ZhipuClient(w.Unwrap())
+	}
+	if z, ok := p.(interface{ Client() *zhipu.Client }); ok {
+		return z.Client(), true
+	}
+	return nil, false
+}
+
+func init() {
+	Register(DefaultProvider, func(settings config.Settings) (Provider, error) {
+		if settings.APIKey == "" {
+			return nil, fmt.Errorf("missing API key. Set ZAI_API_KEY or ZHIPU_API_KEY")
+		}
+		return NewZAI(settings.NewClient()), nil
+	})
+}
+
+// ZAI is the Provider for the Z.AI (Zhipu) API
+type ZAI struct {
+	client *zhipu.Client
+}
+
+// NewZAI wraps a Z.AI client as a Provider
+func NewZAI(client *zhipu.Client) *ZAI {
+	return &ZAI{client: client}
+}
+
+// Name returns "zai"
+func (p *ZAI) Name() string {
+	return DefaultProvider
+}
+
+// Client returns the underlying Z.AI client
+func (p *ZAI) Client() *zhipu.Client {
+	return p.client
+}
+
+// Chat sends a chat completion
+func (p *ZAI) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	return p.client.Chat(ctx, req)
+}
+
+// ChatStream streams a chat completion
+func (p *ZAI) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	return p.client.ChatStream(ctx, req)
+}
+
+// Embeddings embeds texts with the Z.AI embedding models
+func (p *ZAI) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	return p.client.Embeddings(ctx, req)
+}
+
+// Models lists the Z.AI chat models
+func (p *ZAI) Models(ctx context.Context) ([]ModelInfo, error) {
+	return ZAIModels(), nil
+}
+
+// ZAIModels lists the Z.AI chat models without needing a client
+func ZAIModels() []ModelInfo {
+	var models []ModelInfo
+	for _, id := range zhipu.AllModels() {
+		if id == zhipu.ModelEmbedding3 {
+			continue
+		}
+		if info := GetModelInfo(id); info != nil {
+			models = append(models, *info)
+		} else {
+			models = append(models, ModelInfo{ID: id, Name: id, Context: zhipu.ContextWindow(id), Provider: DefaultProvider})
+		}
+	}
+	return models
+}
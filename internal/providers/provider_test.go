@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func TestNew_DefaultsToZAI(t *testing.T) {
+	p, err := New(config.Settings{APIKey: "test-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != DefaultProvider {
+		t.Errorf("Name() = %q, want %q", p.Name(), DefaultProvider)
+	}
+	if _, ok := ZhipuClient(p); !ok {
+		t.Error("ZhipuClient() found no client on the zai provider")
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(config.Settings{Provider: "zai"}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+	if _, err := New(config.Settings{Provider: "nope", APIKey: "k"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on a duplicate name")
+		}
+	}()
+	Register(DefaultProvider, nil)
+}
+
+func TestNames(t *testing.T) {
+	if !slices.Contains(Names(), DefaultProvider) {
+		t.Errorf("Names() = %v, missing %q", Names(), DefaultProvider)
+	}
+}
+
+func TestZAI_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewZAI(zhipu.NewClient("test-key", zhipu.WithBaseURL(server.URL)))
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model:    zhipu.ModelGLM4_32B,
+		Messages: []zhipu.Message{{Role: "user", Content: "hello"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var text string
+	for stream.Next() {
+		if ev := stream.Event(); ev.Type == zhipu.EventTextDelta {
+			text += ev.Text
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if text != "hi" {
+		t.Errorf("text = %q, want %q", text, "hi")
+	}
+}
+
+func TestZAI_Models(t *testing.T) {
+	models, err := NewZAI(zhipu.NewClient("test-key")).Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range models {
+		if m.ID == zhipu.ModelEmbedding3 {
+			t.Error("Models() lists the embedding model")
+		}
+		if m.Context == 0 {
+			t.Errorf("%s has no context size", m.ID)
+		}
+	}
+	if len(models) == 0 {
+		t.Error("Models() returned nothing")
+	}
+}
@@ -0,0 +1,301 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// defaultReplyReserve is the room kept for the reply when history is fitted
+// to a model's window and the request sets no MaxTokens
+const defaultReplyReserve = 4096
+
+// thinkHardPhrases in the last user message ask for a reasoning model
+var thinkHardPhrases = []string{"think hard", "think deeply", "think carefully", "think step by step"}
+
+// RouteDecision records a request sent to another model than the one it
+// named, because the model lacked a capability or failed, or whose history
+// was trimmed to fit the window of the model it was sent to
+type RouteDecision struct {
+	Requested string // Model the request named
+	Model     string // Model it was sent to
+	Reason    string
+	Err       error // Failure that caused a fallback, if any
+	Trimmed   int   // Old messages left out to fit Model's context window
+}
+
+// Rerouted reports whether the request went to another model than it named
+func (d RouteDecision) Rerouted() bool {
+	return d.Model != d.Requested
+}
+
+// String formats the decision for the status bar
+func (d RouteDecision) String() string {
+	var parts []string
+	if d.Rerouted() {
+		parts = append(parts, fmt.Sprintf("%s → %s (%s)", d.Requested, d.Model, d.Reason))
+	}
+	if d.Trimmed > 0 {
+		parts = append(parts, fmt.Sprintf("left out %d old messages to fit the context window", d.Trimmed))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Router is a Provider that sends each request to a model able to serve
+// it: images go to a vision model, function tools to a model that can
+// call them, and "think hard" requests to a reasoning model. Settings.Routing
+// overrides these choices; without it the router stays with the vendor of
+// the requested model. When the model is rate limited or has a server
+// error, the request is retried on each model of Routing.Fallback.
+type Router struct {
+	settings config.Settings
+	primary  Provider
+
+	// OnDecision, when set, is called for every request sent to another
+	// model than it names or with a trimmed history, before it is sent. It
+	// runs on the caller's goroutine.
+	OnDecision func(RouteDecision)
+
+	mu    sync.Mutex
+	cache map[string]Provider // Providers of "<provider>/" models, by name
+}
+
+// NewRouter routes requests for primary, the provider selected by settings
+func NewRouter(settings config.Settings, primary Provider) *Router {
+	return &Router{settings: settings, primary: primary, cache: make(map[string]Provider)}
+}
+
+// Name returns the name of the primary provider
+func (r *Router) Name() string {
+	if r.primary == nil {
+		return "router"
+	}
+	return r.primary.Name()
+}
+
+// Unwrap returns the primary provider
+func (r *Router) Unwrap() Provider {
+	return r.primary
+}
+
+// Select returns the model req should be sent to, without sending it.
+// The decision's Model equals Requested when no rerouting is needed.
+func (r *Router) Select(req *zhipu.ChatRequest) RouteDecision {
+	d := RouteDecision{Requested: req.Model, Model: req.Model}
+	routing := r.settings.Routing
+	switch {
+	case zhipu.HasImages(req.Messages):
+		d.Model, d.Reason = r.capable(req.Model, "vision", routing.Vision), "image attached"
+	case hasFunctionTools(req.Tools):
+		d.Model, d.Reason = r.capable(req.Model, "function_calling", routing.Tools), "tools"
+	case wantsReasoning(req.Messages):
+		d.Model, d.Reason = r.capable(req.Model, "reasoning", routing.Reasoning), "think hard"
+	}
+	if d.Model == req.Model {
+		d.Reason = ""
+	}
+	return d
+}
+
+// capable returns model if it has capability, else the configured model,
+// else a model of the same vendor that has it. Models whose capabilities
+// are unknown are assumed to have it.
+func (r *Router) capable(model, capability, configured string) string {
+	info := r.modelInfo(model)
+	if info == nil || info.HasCapability(capability) {
+		return model
+	}
+	if configured != "" {
+		return configured
+	}
+	if info.Provider == DefaultProvider {
+		switch capability {
+		case "vision":
+			return r.qualify(DefaultProvider, zhipu.VisionModelFor(info.ID))
+		case "function_calling":
+			return r.qualify(DefaultProvider, zhipu.ModelGLM4_32B)
+		case "reasoning":
+			return r.qualify(DefaultProvider, zhipu.ModelGLMZ1_32B)
+		}
+		return model
+	}
+	for _, m := range Catalogue(info.Provider) {
+		if m.HasCapability(capability) {
+			return r.qualify(m.Provider, m.ID)
+		}
+	}
+	return model
+}
+
+// modelInfo looks up model as written, without a "zai/" prefix, and as a
+// model of the primary provider
+func (r *Router) modelInfo(model string) *ModelInfo {
+	if info := GetModelInfo(model); info != nil {
+		return info
+	}
+	if name, ok := strings.CutPrefix(model, DefaultProvider+"/"); ok {
+		return GetModelInfo(name)
+	}
+	if r.primary != nil {
+		return GetModelInfo(r.primary.Name() + "/" + model)
+	}
+	return nil
+}
+
+// qualify writes a model of provider the way ForModel routes it
+func (r *Router) qualify(provider, id string) string {
+	if r.primary != nil && r.primary.Name() == provider {
+		return id
+	}
+	return provider + "/" + id
+}
+
+func hasFunctionTools(tools []zhipu.Tool) bool {
+	for _, tool := range tools {
+		if tool.Type == "function" {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsReasoning reports whether the last user message asks the model to
+// think hard
+func wantsReasoning(messages []zhipu.Message) bool {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		text := strings.ToLower(messages[i].Text())
+		for _, phrase := range thinkHardPhrases {
+			if strings.Contains(text, phrase) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// Chat sends req to the selected model, falling back as configured
+func (r *Router) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	var resp *zhipu.ChatResponse
+	err := r.send(req, func(p Provider, req *zhipu.ChatRequest) (err error) {
+		resp, err = p.Chat(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream streams the reply of the selected model. Only a failure to
+// open the stream falls back; errors in an open stream are returned by it.
+func (r *Router) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	var stream *zhipu.Stream
+	err := r.send(req, func(p Provider, req *zhipu.ChatRequest) (err error) {
+		stream, err = p.ChatStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
+
+// send tries the selected model and then each fallback model, for as long
+// as they are rate limited or fail with a server error. The history is
+// fitted to the window of each model tried, since a fallback may have a
+// smaller one.
+func (r *Router) send(req *zhipu.ChatRequest, do func(Provider, *zhipu.ChatRequest) error) error {
+	d := r.Select(req)
+	reserve := defaultReplyReserve
+	if req.MaxTokens > 0 {
+		reserve = req.MaxTokens
+	}
+	var err error
+	for i, model := range r.chain(d.Model) {
+		if i > 0 {
+			d = RouteDecision{Requested: req.Model, Model: model, Reason: fallbackReason(d.Model, err), Err: err}
+		}
+		p, name, rerr := r.resolve(model)
+		if rerr != nil {
+			return rerr
+		}
+		sent := *req
+		sent.Model = name
+		sent.Messages, d.Trimmed = zhipu.FitWindow(req.Messages, ContextWindow(p, name), reserve)
+		if (d.Rerouted() || d.Trimmed > 0) && r.OnDecision != nil {
+			r.OnDecision(d)
+		}
+		if err = do(p, &sent); err == nil || !canFallBack(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// chain returns model followed by the fallback models not already in it
+func (r *Router) chain(model string) []string {
+	models := []string{model}
+	for _, fallback := range r.settings.Routing.Fallback {
+		if !slices.Contains(models, fallback) {
+			models = append(models, fallback)
+		}
+	}
+	return models
+}
+
+func canFallBack(err error) bool {
+	return errors.Is(err, zhipu.ErrRateLimited) || errors.Is(err, zhipu.ErrServer)
+}
+
+func fallbackReason(model string, err error) string {
+	if errors.Is(err, zhipu.ErrRateLimited) {
+		return model + " rate limited"
+	}
+	return model + " server error"
+}
+
+// resolve returns the provider for model and the name it knows it by,
+// reusing providers built for earlier requests
+func (r *Router) resolve(model string) (Provider, string, error) {
+	def := r.primary
+	if prefix, _, ok := strings.Cut(model, "/"); ok {
+		r.mu.Lock()
+		if p, cached := r.cache[prefix]; cached {
+			def = p
+		}
+		r.mu.Unlock()
+	}
+	p, name, err := ForModel(r.settings, def, model)
+	if err != nil {
+		return nil, "", err
+	}
+	if p == nil {
+		return nil, "", fmt.Errorf("no provider for model %q", model)
+	}
+	if p != r.primary {
+		r.mu.Lock()
+		r.cache[p.Name()] = p
+		r.mu.Unlock()
+	}
+	return p, name, nil
+}
+
+// Embeddings embeds with the primary provider
+func (r *Router) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	if r.primary == nil {
+		return nil, fmt.Errorf("no provider configured")
+	}
+	return r.primary.Embeddings(ctx, req)
+}
+
+// Models lists the models of the primary provider
+func (r *Router) Models(ctx context.Context) ([]ModelInfo, error) {
+	if r.primary == nil {
+		return nil, fmt.Errorf("no provider configured")
+	}
+	return r.primary.Models(ctx)
+}
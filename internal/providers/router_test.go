@@ -0,0 +1,193 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// fakeProvider answers chat requests with errs[model], recording the
+// models it was sent and the number of messages of each request
+type fakeProvider struct {
+	name     string
+	errs     map[string]error
+	sent     []string
+	messages []int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	p.sent = append(p.sent, req.Model)
+	p.messages = append(p.messages, len(req.Messages))
+	if err := p.errs[req.Model]; err != nil {
+		return nil, err
+	}
+	return chatResponse("1", req.Model, zhipu.Message{Role: "assistant", Content: "ok"}, "stop", zhipu.Usage{}), nil
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	p.sent = append(p.sent, req.Model)
+	p.messages = append(p.messages, len(req.Messages))
+	return nil, p.errs[req.Model]
+}
+
+func (p *fakeProvider) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	return nil, errNotSupported(p.name, "embeddings")
+}
+
+func (p *fakeProvider) Models(ctx context.Context) ([]ModelInfo, error) {
+	return nil, nil
+}
+
+func userRequest(model, text string) *zhipu.ChatRequest {
+	return &zhipu.ChatRequest{Model: model, Messages: []zhipu.Message{{Role: "user", Content: text}}}
+}
+
+func TestRouter_Select(t *testing.T) {
+	image := &zhipu.ChatRequest{Messages: []zhipu.Message{{Role: "user", Content: []zhipu.ContentPart{
+		{Type: "image_url", ImageURL: &zhipu.ImageURL{URL: "data:image/png;base64,AAAA"}},
+		{Type: "text", Text: "what is this?"},
+	}}}}
+	withImage := func(model string) *zhipu.ChatRequest {
+		req := *image
+		req.Model = model
+		return &req
+	}
+	withTools := func(model string) *zhipu.ChatRequest {
+		req := userRequest(model, "list the files")
+		req.Tools = []zhipu.Tool{{Type: "function", Function: &zhipu.Function{Name: "ls"}}}
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		primary string
+		routing config.RoutingSettings
+		req     *zhipu.ChatRequest
+		want    string
+	}{
+		{"plain", "zai", config.RoutingSettings{}, userRequest(zhipu.ModelGLM4_32B, "hi"), zhipu.ModelGLM4_32B},
+		{"image", "zai", config.RoutingSettings{}, withImage(zhipu.ModelGLM4_32B), zhipu.ModelGLM4VPlus},
+		{"image on reasoning model", "zai", config.RoutingSettings{}, withImage(zhipu.ModelGLMZ1_32B), zhipu.ModelGLM4VThinking},
+		{"image on vision model", "zai", config.RoutingSettings{}, withImage(zhipu.ModelGLM4V), zhipu.ModelGLM4V},
+		{"image configured", "zai", config.RoutingSettings{Vision: "dashscope/qwen-vl-max"}, withImage(zhipu.ModelGLM4_32B), "dashscope/qwen-vl-max"},
+		{"tools", "zai", config.RoutingSettings{}, withTools(zhipu.ModelGLMZ1_32B), zhipu.ModelGLM4_32B},
+		{"tools supported", "zai", config.RoutingSettings{}, withTools(zhipu.ModelGLM4_9B), zhipu.ModelGLM4_9B},
+		{"think hard", "zai", config.RoutingSettings{}, userRequest(zhipu.ModelGLM4_32B, "Think hard: is P = NP?"), zhipu.ModelGLMZ1_32B},
+		{"think hard configured", "zai", config.RoutingSettings{Reasoning: zhipu.ModelGLMZ1Rumination}, userRequest(zhipu.ModelGLM4_32B, "think hard"), zhipu.ModelGLMZ1Rumination},
+		{"prefixed model", "ollama", config.RoutingSettings{}, withImage("zai/" + zhipu.ModelGLM4_32B), "zai/" + zhipu.ModelGLM4VPlus},
+		{"other vendor", "dashscope", config.RoutingSettings{}, withImage("qwen-max"), "qwen-vl-max"},
+		{"other vendor prefixed", "zai", config.RoutingSettings{}, userRequest("hunyuan/hunyuan-lite", "think hard"), "hunyuan/hunyuan-t1-latest"},
+		{"unknown model", "ollama", config.RoutingSettings{}, withImage("llama3.2"), "llama3.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter(config.Settings{Routing: tt.routing}, &fakeProvider{name: tt.primary})
+			d := r.Select(tt.req)
+			if d.Model != tt.want {
+				t.Errorf("Select() = %q, want %q", d.Model, tt.want)
+			}
+			if (d.Reason != "") != (d.Model != d.Requested) {
+				t.Errorf("Reason = %q for %s", d.Reason, d)
+			}
+		})
+	}
+}
+
+func TestRouter_Fallback(t *testing.T) {
+	primary := &fakeProvider{name: "zai", errs: map[string]error{
+		zhipu.ModelGLM4_32B: &zhipu.APIError{StatusCode: 429, Code: "1302"},
+		zhipu.ModelGLM4_9B:  &zhipu.APIError{StatusCode: 503},
+	}}
+	backup := &fakeProvider{name: "backup"}
+	settings := config.Settings{
+		Providers: map[string]config.ProviderSettings{"backup": {}},
+		Routing:   config.RoutingSettings{Fallback: []string{zhipu.ModelGLM4_32B, zhipu.ModelGLM4_9B, "backup/llama3.2"}},
+	}
+	r := NewRouter(settings, primary)
+	r.cache["backup"] = backup
+	var decisions []RouteDecision
+	r.OnDecision = func(d RouteDecision) { decisions = append(decisions, d) }
+
+	resp, err := r.Chat(context.Background(), userRequest(zhipu.ModelGLM4_32B, "hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "llama3.2" {
+		t.Errorf("Model = %q", resp.Model)
+	}
+	if want := []string{zhipu.ModelGLM4_32B, zhipu.ModelGLM4_9B}; !slices.Equal(primary.sent, want) {
+		t.Errorf("primary sent %v, want %v", primary.sent, want)
+	}
+	if len(decisions) != 2 {
+		t.Fatalf("decisions = %v", decisions)
+	}
+	if d := decisions[0]; d.Model != zhipu.ModelGLM4_9B || !errors.Is(d.Err, zhipu.ErrRateLimited) {
+		t.Errorf("first decision = %+v", d)
+	}
+	if d := decisions[1]; d.Model != "backup/llama3.2" || !errors.Is(d.Err, zhipu.ErrServer) {
+		t.Errorf("second decision = %+v", d)
+	}
+}
+
+func TestRouter_FallbackFitsWindow(t *testing.T) {
+	primary := &fakeProvider{name: "zai", errs: map[string]error{
+		zhipu.ModelGLM4_32B: &zhipu.APIError{StatusCode: 429},
+	}}
+	spark := &fakeProvider{name: "spark"}
+	settings := config.Settings{Routing: config.RoutingSettings{Fallback: []string{"spark/lite"}}}
+	r := NewRouter(settings, primary)
+	r.cache["spark"] = spark
+	var decisions []RouteDecision
+	r.OnDecision = func(d RouteDecision) { decisions = append(decisions, d) }
+
+	// About 6000 tokens: fits GLM-4's window but not Spark Lite's 4096
+	req := &zhipu.ChatRequest{Model: zhipu.ModelGLM4_32B, MaxTokens: 512}
+	for i := 0; i < 100; i++ {
+		req.Messages = append(req.Messages, zhipu.Message{Role: "user", Content: strings.Repeat("word ", 50)})
+	}
+	if _, err := r.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if primary.messages[0] != 100 {
+		t.Errorf("primary got %d messages, want 100", primary.messages[0])
+	}
+	if len(spark.messages) != 1 || spark.messages[0] >= 100 {
+		t.Errorf("fallback got %v messages, want the history trimmed", spark.messages)
+	}
+	if len(req.Messages) != 100 {
+		t.Errorf("request was modified: %d messages", len(req.Messages))
+	}
+	if len(decisions) != 1 || decisions[0].Model != "spark/lite" || decisions[0].Trimmed != 100-spark.messages[0] {
+		t.Errorf("decisions = %+v, want the fallback with its trimmed count", decisions)
+	}
+}
+
+func TestRouter_NoFallbackOnClientError(t *testing.T) {
+	primary := &fakeProvider{name: "zai", errs: map[string]error{
+		zhipu.ModelGLM4_32B: &zhipu.APIError{StatusCode: 401},
+	}}
+	settings := config.Settings{Routing: config.RoutingSettings{Fallback: []string{zhipu.ModelGLM4_9B}}}
+	r := NewRouter(settings, primary)
+
+	_, err := r.ChatStream(context.Background(), userRequest(zhipu.ModelGLM4_32B, "hi"))
+	if !errors.Is(err, zhipu.ErrAuth) {
+		t.Errorf("err = %v, want ErrAuth", err)
+	}
+	if len(primary.sent) != 1 {
+		t.Errorf("sent %v, want one request", primary.sent)
+	}
+}
+
+func TestRouter_ZhipuClient(t *testing.T) {
+	r := NewRouter(config.Settings{}, NewZAI(zhipu.NewClient("test-key")))
+	if _, ok := ZhipuClient(r); !ok {
+		t.Error("ZhipuClient() found no client behind the router")
+	}
+}
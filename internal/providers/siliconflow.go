@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/biodoia/golem/internal/config"
+)
+
+// SiliconFlowBaseURL is SiliconFlow's OpenAI-compatible endpoint
+const SiliconFlowBaseURL = "https://api.siliconflow.cn/v1"
+
+const siliconFlowEmbeddingModel = "BAAI/bge-m3"
+
+func init() {
+	Register("siliconflow", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("SILICONFLOW_API_KEY")
+		}
+		if ps.APIKey == "" {
+			return nil, fmt.Errorf("missing SiliconFlow API key. Set SILICONFLOW_API_KEY")
+		}
+		p := NewSiliconFlow(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// SiliconFlow is the Provider for SiliconFlow, which serves open models
+// from several labs (DeepSeek, Qwen, GLM) behind one OpenAI-compatible API
+type SiliconFlow struct {
+	*OpenAI
+}
+
+// NewSiliconFlow creates a provider called name for the account in ps
+func NewSiliconFlow(name string, ps config.ProviderSettings) *SiliconFlow {
+	if ps.BaseURL == "" {
+		ps.BaseURL = SiliconFlowBaseURL
+	}
+	if ps.EmbeddingModel == "" {
+		ps.EmbeddingModel = siliconFlowEmbeddingModel
+	}
+	return &SiliconFlow{OpenAI: NewOpenAI(name, ps)}
+}
+
+// Models lists the SiliconFlow models in the catalogue. The endpoint
+// serves many more; any of them can be selected by ID.
+func (p *SiliconFlow) Models(ctx context.Context) ([]ModelInfo, error) {
+	return Catalogue("siliconflow"), nil
+}
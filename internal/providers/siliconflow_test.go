@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newSiliconFlowTest(t *testing.T, handler http.HandlerFunc) *SiliconFlow {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewSiliconFlow("siliconflow", config.ProviderSettings{BaseURL: server.URL, APIKey: "sk-test"})
+}
+
+func TestSiliconFlow_Defaults(t *testing.T) {
+	p := NewSiliconFlow("siliconflow", config.ProviderSettings{APIKey: "sk-test"})
+	if p.baseURL != SiliconFlowBaseURL {
+		t.Errorf("baseURL = %q, want %q", p.baseURL, SiliconFlowBaseURL)
+	}
+	if p.embeddingModel != siliconFlowEmbeddingModel {
+		t.Errorf("embeddingModel = %q, want %q", p.embeddingModel, siliconFlowEmbeddingModel)
+	}
+}
+
+func TestSiliconFlow_Chat(t *testing.T) {
+	var got map[string]interface{}
+	p := newSiliconFlowTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("%s, Authorization = %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "siliconflow_chat.json")
+	})
+
+	// The vendor prefix is stripped; the slash inside the model ID is kept
+	provider, model, err := ForModel(config.Settings{}, p, "siliconflow/deepseek-ai/DeepSeek-V3")
+	if err != nil || provider != p || model != "deepseek-ai/DeepSeek-V3" {
+		t.Fatalf("ForModel = %v, %q, %v", provider, model, err)
+	}
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model:    model,
+		Messages: []zhipu.Message{{Role: "user", Content: "What is Go?"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["model"] != "deepseek-ai/DeepSeek-V3" {
+		t.Errorf("model = %v", got["model"])
+	}
+	if content, _ := resp.Choices[0].Message.Content.(string); content != "Go is a statically typed, compiled language." || resp.Usage.TotalTokens != 23 {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestSiliconFlow_Embeddings(t *testing.T) {
+	var got map[string]interface{}
+	p := newSiliconFlowTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "siliconflow_embeddings.json")
+	})
+
+	resp, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"hello", "world"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["model"] != siliconFlowEmbeddingModel {
+		t.Errorf("model = %v, want %s", got["model"], siliconFlowEmbeddingModel)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1][2] != -0.0987 {
+		t.Errorf("Embeddings = %v", resp.Embeddings)
+	}
+}
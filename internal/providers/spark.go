@@ -0,0 +1,171 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// SparkBaseURL is iFlytek's OpenAI-compatible Spark endpoint
+const SparkBaseURL = "https://spark-api-open.xf-yun.com/v1"
+
+// sparkCodes maps Spark error codes to the zhipu categories
+var sparkCodes = map[string]error{
+	"10013": zhipu.ErrContentFiltered, // input failed moderation
+	"10014": zhipu.ErrContentFiltered, // output failed moderation
+	"10019": zhipu.ErrContentFiltered, // output may be sensitive
+	"10907": zhipu.ErrContextLength,
+	"11200": zhipu.ErrAuth,          // not authorized for the model
+	"11201": zhipu.ErrQuotaExceeded, // daily limit reached
+	"11202": zhipu.ErrRateLimited,   // QPS limit
+	"11203": zhipu.ErrRateLimited,   // concurrency limit
+}
+
+func init() {
+	Register("spark", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("SPARK_API_PASSWORD")
+		}
+		if ps.APIKey == "" {
+			return nil, fmt.Errorf("missing Spark APIPassword. Set SPARK_API_PASSWORD")
+		}
+		p := NewSpark(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// Spark is the Provider for iFlytek's Spark models. The HTTP API follows
+// OpenAI's, except that errors come back with HTTP 200 as a top-level
+// code and a reply's tool call is an object rather than a list.
+type Spark struct {
+	*OpenAI
+}
+
+// NewSpark creates a provider called name for the APIPassword in ps
+func NewSpark(name string, ps config.ProviderSettings) *Spark {
+	if ps.BaseURL == "" {
+		ps.BaseURL = SparkBaseURL
+	}
+	return &Spark{OpenAI: NewOpenAI(name, ps)}
+}
+
+// Chat sends a chat request and waits for the whole reply
+func (p *Spark) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire := toOpenAIRequest(req)
+	wire.Stream = false
+	wire.StreamOptions = nil
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", wire, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if data, err = normalizeSparkPayload(data); err != nil {
+		return nil, err
+	}
+	var chatResp zhipu.ChatResponse
+	if err := json.Unmarshal(data, &chatResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &chatResp, nil
+}
+
+// ChatStream streams a chat reply. A request rejected up front is
+// answered with a JSON error instead of a stream.
+func (p *Spark) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	wire := toOpenAIRequest(req)
+	wire.StreamOptions = nil // Spark always sends usage with the last chunk
+	resp, err := p.do(ctx, http.MethodPost, "/chat/completions", wire, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	if isJSON(resp) {
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		if _, err := normalizeSparkPayload(data); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: expected an event stream", p.name)
+	}
+	return zhipu.NewSSEStream(resp.Body, req.RequestID, decodeSparkChunk), nil
+}
+
+func decodeSparkChunk(payload []byte) ([]zhipu.StreamEvent, error) {
+	payload, err := normalizeSparkPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return zhipu.DecodeChatChunk(payload)
+}
+
+// normalizeSparkPayload returns the error a response's code reports, or
+// the response with tool calls in OpenAI's list form
+func normalizeSparkPayload(payload []byte) ([]byte, error) {
+	var reply map[string]interface{}
+	if err := json.Unmarshal(payload, &reply); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if code, _ := reply["code"].(float64); code != 0 {
+		message, _ := reply["message"].(string)
+		sid, _ := reply["sid"].(string)
+		return nil, categorize(&zhipu.APIError{
+			StatusCode: http.StatusOK,
+			Code:       strconv.Itoa(int(code)),
+			Message:    message,
+			RequestID:  sid,
+		}, sparkCodes)
+	}
+
+	changed := false
+	choices, _ := reply["choices"].([]interface{})
+	for _, c := range choices {
+		choice, _ := c.(map[string]interface{})
+		for _, key := range []string{"delta", "message"} {
+			msg, _ := choice[key].(map[string]interface{})
+			call, ok := msg["tool_calls"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, ok := call["id"]; !ok {
+				call["id"] = "call_0"
+			}
+			call["index"] = 0
+			msg["tool_calls"] = []interface{}{call}
+			changed = true
+		}
+	}
+	if !changed {
+		return payload, nil
+	}
+	return json.Marshal(reply)
+}
+
+// Embeddings is not offered by the Spark HTTP API
+func (p *Spark) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	return nil, errNotSupported(p.name, "embeddings")
+}
+
+// Models lists the Spark models in the catalogue
+func (p *Spark) Models(ctx context.Context) ([]ModelInfo, error) {
+	return Catalogue("spark"), nil
+}
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+func newSparkTest(t *testing.T, handler http.HandlerFunc) *Spark {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewSpark("spark", config.ProviderSettings{BaseURL: server.URL, APIKey: "key:secret"})
+}
+
+func TestSpark_ChatStream(t *testing.T) {
+	var got map[string]interface{}
+	p := newSparkTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key:secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "spark_stream.txt")
+	})
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model:    "4.0Ultra",
+		Messages: []zhipu.Message{{Role: "user", Content: "你好"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if _, ok := got["stream_options"]; ok {
+		t.Error("request has stream_options")
+	}
+	if result.Content != "你好，世界" || result.FinishReason != "stop" {
+		t.Errorf("Content = %q, FinishReason = %q", result.Content, result.FinishReason)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 10 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestSpark_ToolCall(t *testing.T) {
+	p := newSparkTest(t, func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "spark_tool_chat.json")
+	})
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model:    "generalv3.5",
+		Messages: []zhipu.Message{{Role: "user", Content: "read go.mod"}},
+		Tools:    []zhipu.Tool{zhipu.NewFunctionTool("read_file", "Read a file", zhipu.NewObjectSchema(nil, nil))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_0" || calls[0].Function.Name != "read_file" || calls[0].Function.Arguments != `{"path":"go.mod"}` {
+		t.Errorf("ToolCalls = %+v", calls)
+	}
+}
+
+func TestSpark_Errors(t *testing.T) {
+	p := newSparkTest(t, func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(t, w, "spark_error.json")
+	})
+	req := &zhipu.ChatRequest{Model: "lite"}
+	if _, err := p.Chat(context.Background(), req); !errors.Is(err, zhipu.ErrRateLimited) {
+		t.Errorf("Chat err = %v, want ErrRateLimited", err)
+	}
+	if _, err := p.ChatStream(context.Background(), req); !errors.Is(err, zhipu.ErrRateLimited) {
+		t.Errorf("ChatStream err = %v, want ErrRateLimited", err)
+	}
+	if _, err := p.Embeddings(context.Background(), &zhipu.EmbeddingRequest{Input: []string{"a"}}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Embeddings err = %v, want ErrUnsupported", err)
+	}
+}
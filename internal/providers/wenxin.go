@@ -0,0 +1,429 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// WenxinBaseURL is Baidu Qianfan's ERNIE endpoint
+const WenxinBaseURL = "https://aip.baidubce.com/rpc/2.0/ai_custom/v1/wenxinworkshop"
+
+// wenxinEndpoints maps model IDs to the endpoint names Qianfan serves them
+// under; other models are assumed to be served under their ID
+var wenxinEndpoints = map[string]string{
+	"ernie-4.0-8k": "completions_pro",
+	"ernie-3.5-8k": "completions",
+}
+
+// wenxinCodes maps Qianfan error codes to the zhipu categories
+var wenxinCodes = map[string]error{
+	"6":      zhipu.ErrAuth,          // no permission
+	"14":     zhipu.ErrAuth,          // IAM authentication failed
+	"110":    zhipu.ErrAuth,          // access token invalid
+	"111":    zhipu.ErrAuth,          // access token expired
+	"17":     zhipu.ErrQuotaExceeded, // daily request limit
+	"19":     zhipu.ErrQuotaExceeded, // total request limit
+	"18":     zhipu.ErrRateLimited,   // QPS limit
+	"336501": zhipu.ErrRateLimited,   // RPM limit
+	"336502": zhipu.ErrRateLimited,   // TPM limit
+	"336100": zhipu.ErrServer,
+	"336103": zhipu.ErrContextLength,
+	"336104": zhipu.ErrContentFiltered,
+}
+
+// wenxinMinTemperature stands in for 0, which Qianfan rejects
+const wenxinMinTemperature = 0.01
+
+func init() {
+	Register("wenxin", func(settings config.Settings) (Provider, error) {
+		ps := settings.ActiveProvider()
+		if ps.APIKey == "" {
+			ps.APIKey = os.Getenv("QIANFAN_AK")
+		}
+		if ps.SecretKey == "" {
+			ps.SecretKey = os.Getenv("QIANFAN_SK")
+		}
+		if ps.APIKey == "" || ps.SecretKey == "" {
+			return nil, fmt.Errorf("missing Qianfan credentials. Set QIANFAN_AK and QIANFAN_SK")
+		}
+		p := NewWenxin(settings.Provider, ps)
+		p.configure(settings)
+		return p, nil
+	})
+}
+
+// Wenxin is the Provider for Baidu's ERNIE models on Qianfan. Requests
+// carry an OAuth access token obtained with the API key and secret key,
+// which is cached and renewed when Qianfan reports it expired.
+type Wenxin struct {
+	httpBackend
+	apiKey    string
+	secretKey string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewWenxin creates a provider called name for the application in ps,
+// whose APIKey is the API Key and SecretKey the Secret Key
+func NewWenxin(name string, ps config.ProviderSettings) *Wenxin {
+	return &Wenxin{
+		httpBackend: newHTTPBackend(name, ps, WenxinBaseURL),
+		apiKey:      ps.APIKey,
+		secretKey:   ps.SecretKey,
+	}
+}
+
+type wenxinRequest struct {
+	Messages        []wenxinMessage   `json:"messages"`
+	System          string            `json:"system,omitempty"`
+	Temperature     *float64          `json:"temperature,omitempty"`
+	TopP            *float64          `json:"top_p,omitempty"`
+	MaxOutputTokens int               `json:"max_output_tokens,omitempty"`
+	Stop            []string          `json:"stop,omitempty"`
+	Stream          bool              `json:"stream,omitempty"`
+	Functions       []*zhipu.Function `json:"functions,omitempty"`
+	ToolChoice      *zhipu.ToolChoice `json:"tool_choice,omitempty"`
+	UserID          string            `json:"user_id,omitempty"`
+}
+
+type wenxinMessage struct {
+	Role         string              `json:"role"` // "user", "assistant" or "function"
+	Content      string              `json:"content"`
+	Name         string              `json:"name,omitempty"` // Function name of a function result
+	FunctionCall *wenxinFunctionCall `json:"function_call,omitempty"`
+}
+
+type wenxinFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Thoughts  string `json:"thoughts,omitempty"`
+}
+
+// wenxinReply is a whole reply, or one chunk of a streamed one
+type wenxinReply struct {
+	ID           string              `json:"id"`
+	Result       string              `json:"result"`
+	IsEnd        bool                `json:"is_end"`
+	FinishReason string              `json:"finish_reason"`
+	FunctionCall *wenxinFunctionCall `json:"function_call"`
+	Usage        zhipu.Usage         `json:"usage"`
+	ErrorCode    int                 `json:"error_code"`
+	ErrorMsg     string              `json:"error_msg"`
+}
+
+// toWenxinRequest translates req. The system prompt moves to its own
+// field, tool results become "function" messages, consecutive messages
+// of one role are merged since turns must alternate, and temperature 0 is
+// raised to Qianfan's minimum.
+func toWenxinRequest(req *zhipu.ChatRequest) *wenxinRequest {
+	out := &wenxinRequest{
+		TopP:            req.TopP,
+		MaxOutputTokens: req.MaxTokens,
+		Stop:            req.Stop,
+		Stream:          req.Stream,
+		UserID:          req.UserID,
+	}
+
+	callNames := make(map[string]string)
+	var system []string
+	for _, msg := range req.Messages {
+		wm := wenxinMessage{Role: msg.Role, Content: msg.Text()}
+		switch msg.Role {
+		case "system":
+			system = append(system, wm.Content)
+			continue
+		case "tool":
+			wm.Role = "function"
+			wm.Name = callNames[msg.ToolCallID]
+		case "assistant":
+			if len(msg.ToolCalls) > 0 {
+				tc := msg.ToolCalls[0] // ERNIE makes one call per turn
+				wm.FunctionCall = &wenxinFunctionCall{Name: tc.Function.Name, Arguments: tc.Function.Arguments}
+				callNames[tc.ID] = tc.Function.Name
+			}
+		}
+		if n := len(out.Messages); n > 0 && wm.Role != "function" && wm.FunctionCall == nil {
+			if last := &out.Messages[n-1]; last.Role == wm.Role && last.FunctionCall == nil {
+				last.Content += "\n\n" + wm.Content
+				continue
+			}
+		}
+		out.Messages = append(out.Messages, wm)
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		if tool.Type == "function" && tool.Function != nil {
+			out.Functions = append(out.Functions, tool.Function)
+		}
+	}
+	if len(out.Functions) > 0 && req.ToolChoice != nil && req.ToolChoice.Function != "" {
+		out.ToolChoice = req.ToolChoice
+	}
+
+	switch {
+	case req.Temperature != nil:
+		out.Temperature = zhipu.Float64(max(*req.Temperature, wenxinMinTemperature))
+	case req.DoSample != nil && !*req.DoSample:
+		out.Temperature = zhipu.Float64(wenxinMinTemperature)
+	}
+	return out
+}
+
+func (r *wenxinReply) err() error {
+	if r.ErrorCode == 0 {
+		return nil
+	}
+	return categorize(&zhipu.APIError{
+		StatusCode: http.StatusOK,
+		Code:       strconv.Itoa(r.ErrorCode),
+		Message:    r.ErrorMsg,
+	}, wenxinCodes)
+}
+
+func (r *wenxinReply) toolCalls() []zhipu.ToolCall {
+	if r.FunctionCall == nil {
+		return nil
+	}
+	call := zhipu.ToolCall{ID: "call_0", Type: "function"}
+	call.Function.Name = r.FunctionCall.Name
+	call.Function.Arguments = r.FunctionCall.Arguments
+	return []zhipu.ToolCall{call}
+}
+
+func (r *wenxinReply) finishReason() string {
+	if r.FunctionCall != nil {
+		return "tool_calls"
+	}
+	if r.FinishReason == "" || r.FinishReason == "normal" {
+		return "stop"
+	}
+	return r.FinishReason
+}
+
+// Chat sends a chat request and waits for the whole reply
+func (p *Wenxin) Chat(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.ChatResponse, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	wire := toWenxinRequest(req)
+	wire.Stream = false
+	resp, err := p.do(ctx, p.chatPath(req.Model), wire)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var reply wenxinReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	msg := zhipu.Message{Role: "assistant", Content: reply.Result, ToolCalls: reply.toolCalls()}
+	if reply.FunctionCall != nil {
+		msg.ReasoningContent = reply.FunctionCall.Thoughts
+	}
+	return chatResponse(reply.ID, req.Model, msg, reply.finishReason(), reply.Usage), nil
+}
+
+// ChatStream streams a chat reply. ERNIE sends a function call whole, in
+// the last chunk.
+func (p *Wenxin) ChatStream(ctx context.Context, req *zhipu.ChatRequest) (*zhipu.Stream, error) {
+	req.Stream = true
+	resp, err := p.do(ctx, p.chatPath(req.Model), toWenxinRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return zhipu.NewSSEStream(resp.Body, req.RequestID, decodeWenxinChunk), nil
+}
+
+func decodeWenxinChunk(payload []byte) ([]zhipu.StreamEvent, error) {
+	var chunk wenxinReply
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return nil, fmt.Errorf("decode stream: %w", err)
+	}
+	if err := chunk.err(); err != nil {
+		return nil, err
+	}
+
+	var events []zhipu.StreamEvent
+	if chunk.Result != "" {
+		events = append(events, zhipu.StreamEvent{Type: zhipu.EventTextDelta, Text: chunk.Result})
+	}
+	for i, call := range chunk.toolCalls() {
+		events = append(events, zhipu.StreamEvent{Type: zhipu.EventToolCall, ToolCallIndex: i, ToolCall: &call})
+	}
+	if chunk.IsEnd || chunk.FunctionCall != nil {
+		usage := chunk.Usage
+		events = append(events,
+			zhipu.StreamEvent{Type: zhipu.EventFinish, FinishReason: chunk.finishReason()},
+			zhipu.StreamEvent{Type: zhipu.EventUsage, Usage: &usage})
+	}
+	return events, nil
+}
+
+// Embeddings embeds req.Input with Embedding-V1. Qianfan serves other
+// embedding models under their own endpoints, so req.Model is taken as
+// an endpoint name when set.
+func (p *Wenxin) Embeddings(ctx context.Context, req *zhipu.EmbeddingRequest) (*zhipu.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = "embedding-v1"
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	resp, err := p.do(ctx, "/embeddings/"+model, map[string][]string{"input": req.Input})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var wire struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage zhipu.Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&wire); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	result := &zhipu.EmbeddingResponse{
+		Model:      model,
+		Embeddings: make([][]float32, len(req.Input)),
+		Usage:      wire.Usage,
+	}
+	if len(wire.Data) != len(req.Input) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(wire.Data), len(req.Input))
+	}
+	indices := make([]int, len(wire.Data))
+	for i, d := range wire.Data {
+		indices[i] = d.Index
+	}
+	for i, pos := range embeddingOrder(indices) {
+		result.Embeddings[pos] = wire.Data[i].Embedding
+	}
+	return result, nil
+}
+
+// Models lists the ERNIE models in the catalogue
+func (p *Wenxin) Models(ctx context.Context) ([]ModelInfo, error) {
+	return Catalogue("wenxin"), nil
+}
+
+func (p *Wenxin) chatPath(model string) string {
+	if endpoint, ok := wenxinEndpoints[model]; ok {
+		return "/chat/" + endpoint
+	}
+	return "/chat/" + model
+}
+
+// do posts body to path with an access token, fetching a new token and
+// retrying once if Qianfan rejects the cached one. Errors reported in a
+// JSON body, even with HTTP 200, are returned as errors. On success the
+// caller must close resp.Body.
+func (p *Wenxin) do(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		httpReq, _, err := p.newRequest(ctx, http.MethodPost, path+"?access_token="+url.QueryEscape(token), body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.send(httpReq, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !isJSON(resp) {
+			return resp, nil
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		var reply wenxinReply
+		if json.Unmarshal(data, &reply) == nil && reply.ErrorCode != 0 {
+			if (reply.ErrorCode == 110 || reply.ErrorCode == 111) && attempt == 0 {
+				p.resetToken()
+				continue
+			}
+			return nil, reply.err()
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return resp, nil
+	}
+}
+
+// accessToken returns the cached token, or fetches one from the OAuth
+// endpoint on the same host as the API
+func (p *Wenxin) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	base, err := url.Parse(p.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	tokenURL := base.Scheme + "://" + base.Host + "/oauth/2.0/token?" + url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.apiKey},
+		"client_secret": {p.secretKey},
+	}.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("%s: get access token: %w", p.name, redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	var wire struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&wire); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	if wire.AccessToken == "" {
+		return "", &vendorError{
+			APIError: &zhipu.APIError{StatusCode: resp.StatusCode, Code: wire.Error, Message: wire.ErrorDescription},
+			category: zhipu.ErrAuth,
+		}
+	}
+	p.token = wire.AccessToken
+	// Renew a minute early so a token never expires in flight
+	p.tokenExpiry = time.Now().Add(time.Duration(wire.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
+
+func (p *Wenxin) resetToken() {
+	p.mu.Lock()
+	p.token = ""
+	p.mu.Unlock()
+}
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// newWenxinTest serves the OAuth endpoint, issuing "token-1", "token-2"
+// and so on, and hands chat requests carrying a token to handler
+func newWenxinTest(t *testing.T, handler http.HandlerFunc) (*Wenxin, *int) {
+	t.Helper()
+	tokens := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/2.0/token", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("grant_type") != "client_credentials" || q.Get("client_id") != "ak" || q.Get("client_secret") != "sk" {
+			t.Errorf("token query = %v", q)
+		}
+		tokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token-%d", tokens), "expires_in": 2592000})
+	})
+	mux.HandleFunc("/wenxinworkshop/", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewWenxin("wenxin", config.ProviderSettings{BaseURL: server.URL + "/wenxinworkshop", APIKey: "ak", SecretKey: "sk"}), &tokens
+}
+
+func TestWenxin_ChatStream(t *testing.T) {
+	var got wenxinRequest
+	p, _ := newWenxinTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wenxinworkshop/chat/completions_pro" || r.URL.Query().Get("access_token") != "token-1" {
+			t.Errorf("URL = %s", r.URL)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "wenxin_stream.txt")
+	})
+
+	stream, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{
+		Model: "ernie-4.0-8k",
+		Messages: []zhipu.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "What is"},
+			{Role: "user", Content: "Go?"},
+		},
+		Temperature: zhipu.Float64(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := zhipu.CollectStream(stream)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	if got.System != "Be brief." || len(got.Messages) != 1 || got.Messages[0].Content != "What is\n\nGo?" {
+		t.Errorf("request = %+v", got)
+	}
+	if got.Temperature == nil || *got.Temperature != wenxinMinTemperature {
+		t.Errorf("temperature = %v, want the minimum", got.Temperature)
+	}
+	if result.Content != "Go 是一门编程语言。" || result.FinishReason != "stop" {
+		t.Errorf("Content = %q, FinishReason = %q", result.Content, result.FinishReason)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 14 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestWenxin_FunctionCall(t *testing.T) {
+	var got wenxinRequest
+	p, _ := newWenxinTest(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		serveFixture(t, w, "wenxin_function_call.json")
+	})
+
+	call := zhipu.ToolCall{ID: "call_0", Type: "function"}
+	call.Function.Name = "list_dir"
+	call.Function.Arguments = `{"path":"."}`
+	resp, err := p.Chat(context.Background(), &zhipu.ChatRequest{
+		Model: "ernie-3.5-8k",
+		Messages: []zhipu.Message{
+			{Role: "user", Content: "show go.mod"},
+			{Role: "assistant", ToolCalls: []zhipu.ToolCall{call}},
+			{Role: "tool", ToolCallID: "call_0", Content: "go.mod main.go"},
+		},
+		Tools: []zhipu.Tool{zhipu.NewFunctionTool("read_file", "Read a file", zhipu.NewObjectSchema(nil, nil))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Functions) != 1 || got.Messages[1].FunctionCall == nil || got.Messages[2].Role != "function" || got.Messages[2].Name != "list_dir" {
+		t.Errorf("request = %+v", got)
+	}
+	choice := resp.Choices[0]
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path":"go.mod"}` || choice.FinishReason != "tool_calls" {
+		t.Errorf("reply = %+v", choice)
+	}
+	if choice.Message.ReasoningContent == "" {
+		t.Error("thoughts were dropped")
+	}
+}
+
+func TestWenxin_TokenRefresh(t *testing.T) {
+	calls := 0
+	p, tokens := newWenxinTest(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("access_token") == "token-1" {
+			serveFixture(t, w, "wenxin_token_expired.json")
+			return
+		}
+		serveFixture(t, w, "wenxin_function_call.json")
+	})
+	if _, err := p.Chat(context.Background(), &zhipu.ChatRequest{Model: "ernie-3.5-8k"}); err != nil {
+		t.Fatal(err)
+	}
+	if *tokens != 2 || calls != 2 {
+		t.Errorf("fetched %d tokens for %d calls, want 2 and 2", *tokens, calls)
+	}
+}
+
+func TestWenxin_Error(t *testing.T) {
+	p, _ := newWenxinTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error_code":336501,"error_msg":"Rate limit reached for RPM"}`))
+	})
+	_, err := p.ChatStream(context.Background(), &zhipu.ChatRequest{Model: "ernie-speed-128k"})
+	if !errors.Is(err, zhipu.ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+}
+
+// tokenOnlyTransport issues a token and fails every other request
+type tokenOnlyTransport struct{ issue bool }
+
+func (tr tokenOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tr.issue && strings.HasPrefix(req.URL.Path, "/oauth/") {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"access_token":"SECRETTOKEN","expires_in":2592000}`)),
+			Request:    req,
+		}, nil
+	}
+	return nil, errors.New("connection refused")
+}
+
+func TestWenxin_TransportErrorHidesCredentials(t *testing.T) {
+	for name, issue := range map[string]bool{"token": false, "chat": true} {
+		t.Run(name, func(t *testing.T) {
+			p := NewWenxin("wenxin", config.ProviderSettings{BaseURL: "http://qianfan.invalid/wenxinworkshop", APIKey: "ak", SecretKey: "SUPERSECRET"})
+			p.httpClient = &http.Client{Transport: tokenOnlyTransport{issue: issue}}
+			p.retry = fastRetryPolicy()
+			_, err := p.Chat(context.Background(), &zhipu.ChatRequest{Model: "ernie-4.0-8k", Messages: []zhipu.Message{{Role: "user", Content: "hi"}}})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if msg := err.Error(); strings.Contains(msg, "SUPERSECRET") || strings.Contains(msg, "SECRETTOKEN") || !strings.Contains(msg, "connection refused") {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
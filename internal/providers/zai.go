@@ -20,18 +20,25 @@ type ToolCall struct {
 }
 
 // ZAIProvider wraps the Z.AI API client with enhanced features
+//
+// Deprecated: ZAIProvider keeps its own history and does not implement
+// Provider. Use ZAI, which does.
 type ZAIProvider struct {
 	client      *zhipu.Client
 	model       string
 	temperature float64
 	tools       []zhipu.Tool
+	toolChoice  *zhipu.ToolChoice
+	parallel    *bool
 	history     []zhipu.Message
 }
 
 // NewZAIProvider creates a new Z.AI provider
-func NewZAIProvider(apiKey string) *ZAIProvider {
+//
+// Deprecated: use NewZAI.
+func NewZAIProvider(apiKey string, opts ...zhipu.Option) *ZAIProvider {
 	return &ZAIProvider{
-		client:      zhipu.NewClient(apiKey),
+		client:      zhipu.NewClient(apiKey, opts...),
 		model:       zhipu.ModelGLM4_32B,
 		temperature: 0.7,
 		history:     make([]zhipu.Message, 0),
@@ -53,6 +60,18 @@ func (p *ZAIProvider) RegisterTools(tools []zhipu.Tool) {
 	p.tools = tools
 }
 
+// SetToolChoice sets the tool choice for the first request of the next
+// ChatWithTools call, e.g. zhipu.ForceTool("read_file") to always read
+// before editing. Follow-up requests in the same call use "auto".
+func (p *ZAIProvider) SetToolChoice(choice *zhipu.ToolChoice) {
+	p.toolChoice = choice
+}
+
+// SetParallelToolCalls allows or forbids several tool calls in one turn
+func (p *ZAIProvider) SetParallelToolCalls(parallel bool) {
+	p.parallel = &parallel
+}
+
 // ClearHistory resets conversation history
 func (p *ZAIProvider) ClearHistory() {
 	p.history = make([]zhipu.Message, 0)
@@ -74,12 +93,13 @@ func (p *ZAIProvider) Chat(ctx context.Context, model string, input string) (*zh
 	req := &zhipu.ChatRequest{
 		Model:       model,
 		Messages:    messages,
-		Temperature: p.temperature,
+		Temperature: zhipu.Float64(p.temperature),
 	}
 
 	if len(p.tools) > 0 {
 		req.Tools = p.tools
-		req.ToolChoice = "auto"
+		req.ToolChoice = zhipu.ToolChoiceAuto()
+		req.ParallelToolCalls = p.parallel
 	}
 
 	resp, err := p.client.Chat(ctx, req)
@@ -102,49 +122,59 @@ func (p *ZAIProvider) ChatStream(ctx context.Context, input string, callback Str
 	req := &zhipu.ChatRequest{
 		Model:       p.model,
 		Messages:    messages,
-		Temperature: p.temperature,
+		Temperature: zhipu.Float64(p.temperature),
 		Stream:      true,
 	}
 
-	textCh, errCh := p.client.ChatStream(ctx, req)
-
-	var fullResponse string
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				// Stream ended
-				if fullResponse != "" {
-					p.history = append(messages, zhipu.Message{Role: "assistant", Content: fullResponse})
-				}
-				return nil
-			}
-			fullResponse += text
+	stream, err := p.client.ChatStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var fullResponse, reasoning string
+	for stream.Next() {
+		ev := stream.Event()
+		switch ev.Type {
+		case zhipu.EventTextDelta:
+			fullResponse += ev.Text
 			if callback != nil {
-				callback(text)
+				callback(ev.Text)
 			}
-		case err := <-errCh:
-			if err != nil {
-				return err
-			}
-		case <-ctx.Done():
-			return ctx.Err()
+		case zhipu.EventReasoningDelta:
+			reasoning += ev.Text
 		}
 	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+
+	if fullResponse != "" {
+		p.history = append(messages, zhipu.Message{Role: "assistant", Content: fullResponse, ReasoningContent: reasoning})
+	}
+	return nil
 }
 
 // ChatWithTools sends a request and handles tool calls
 func (p *ZAIProvider) ChatWithTools(ctx context.Context, input string, executor ToolExecutor) (*zhipu.ChatResponse, error) {
 	messages := append(p.history, zhipu.Message{Role: "user", Content: input})
 
+	toolChoice := p.toolChoice
+	p.toolChoice = nil
+	if toolChoice == nil {
+		toolChoice = zhipu.ToolChoiceAuto()
+	}
+
 	for {
 		req := &zhipu.ChatRequest{
-			Model:       p.model,
-			Messages:    messages,
-			Temperature: p.temperature,
-			Tools:       p.tools,
-			ToolChoice:  "auto",
+			Model:             p.model,
+			Messages:          messages,
+			Temperature:       zhipu.Float64(p.temperature),
+			Tools:             p.tools,
+			ToolChoice:        toolChoice,
+			ParallelToolCalls: p.parallel,
 		}
+		toolChoice = zhipu.ToolChoiceAuto()
 
 		resp, err := p.client.Chat(ctx, req)
 		if err != nil {
@@ -191,62 +221,52 @@ type ToolExecutor interface {
 }
 
 // ChatStreamWithTools sends a streaming request with tool call support
-// Uses the enhanced client method that properly handles tool calls in SSE stream
+// Tool calls are delivered once their streamed fragments are complete
 func (p *ZAIProvider) ChatStreamWithTools(ctx context.Context, input string, textCallback StreamCallback, toolCallback func(zhipu.ToolCall)) error {
 	messages := append(p.history, zhipu.Message{Role: "user", Content: input})
 
 	req := &zhipu.ChatRequest{
 		Model:       p.model,
 		Messages:    messages,
-		Temperature: p.temperature,
+		Temperature: zhipu.Float64(p.temperature),
 		Tools:       p.tools,
-		ToolChoice:  "auto",
+		ToolChoice:  zhipu.ToolChoiceAuto(),
 		Stream:      true,
 	}
 
-	textCh, toolCh, errCh := p.client.ChatStreamWithTools(ctx, req)
+	stream, err := p.client.ChatStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
 
-	var fullResponse string
+	var fullResponse, reasoning string
 	var toolCalls []zhipu.ToolCall
 
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				textCh = nil
-			} else if text != "" {
-				fullResponse += text
-				if textCallback != nil {
-					textCallback(text)
-				}
+	for stream.Next() {
+		ev := stream.Event()
+		switch ev.Type {
+		case zhipu.EventTextDelta:
+			fullResponse += ev.Text
+			if textCallback != nil {
+				textCallback(ev.Text)
 			}
-		case tc, ok := <-toolCh:
-			if !ok {
-				toolCh = nil
-			} else {
-				toolCalls = append(toolCalls, tc)
-				if toolCallback != nil {
-					toolCallback(tc)
-				}
+		case zhipu.EventReasoningDelta:
+			reasoning += ev.Text
+		case zhipu.EventToolCall:
+			toolCalls = append(toolCalls, *ev.ToolCall)
+			if toolCallback != nil {
+				toolCallback(*ev.ToolCall)
 			}
-		case err, ok := <-errCh:
-			if ok && err != nil {
-				return err
-			}
-			errCh = nil
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-
-		// All channels closed
-		if textCh == nil && toolCh == nil && errCh == nil {
-			break
 		}
 	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
 
 	// Update history with assistant response
 	if fullResponse != "" || len(toolCalls) > 0 {
-		msg := zhipu.Message{Role: "assistant", Content: fullResponse}
+		msg := zhipu.Message{Role: "assistant", Content: fullResponse, ReasoningContent: reasoning}
 		if len(toolCalls) > 0 {
 			msg.ToolCalls = toolCalls
 		}
@@ -268,6 +288,17 @@ type ModelInfo struct {
 	Description string
 	Capabilities []string
 	Context     int
+	Provider    string // Registry name of the provider serving the model
+}
+
+// HasCapability reports whether the model lists capability, e.g. "vision"
+func (m ModelInfo) HasCapability(capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
 }
 
 // GetModelInfo returns detailed model information
@@ -278,40 +309,69 @@ func GetModelInfo(modelID string) *ModelInfo {
 			Name:        "GLM-4-32B",
 			Description: "Dialogue, code generation, function calling",
 			Capabilities: []string{"chat", "code", "function_calling"},
-			Context:     128000,
+			Context:     zhipu.ContextWindow(zhipu.ModelGLM4_32B),
+		},
+		zhipu.ModelGLM4_9B: {
+			ID:          zhipu.ModelGLM4_9B,
+			Name:        "GLM-4-9B",
+			Description: "Fast dialogue and function calling",
+			Capabilities: []string{"chat", "function_calling"},
+			Context:     zhipu.ContextWindow(zhipu.ModelGLM4_9B),
 		},
 		zhipu.ModelGLMZ1_32B: {
 			ID:          zhipu.ModelGLMZ1_32B,
 			Name:        "GLM-Z1-32B",
 			Description: "Deep thinking, math, complex reasoning",
 			Capabilities: []string{"reasoning", "math", "analysis"},
-			Context:     128000,
+			Context:     zhipu.ContextWindow(zhipu.ModelGLMZ1_32B),
 		},
 		zhipu.ModelGLMZ1Rumination: {
 			ID:          zhipu.ModelGLMZ1Rumination,
 			Name:        "GLM-Z1-Rumination",
 			Description: "Research mode with web search augmentation",
 			Capabilities: []string{"reasoning", "web_search", "research"},
-			Context:     128000,
+			Context:     zhipu.ContextWindow(zhipu.ModelGLMZ1Rumination),
+		},
+		zhipu.ModelGLMZ1_9B: {
+			ID:          zhipu.ModelGLMZ1_9B,
+			Name:        "GLM-Z1-9B",
+			Description: "Lightweight deep thinking",
+			Capabilities: []string{"reasoning", "math"},
+			Context:     zhipu.ContextWindow(zhipu.ModelGLMZ1_9B),
 		},
 		zhipu.ModelGLM4V: {
 			ID:          zhipu.ModelGLM4V,
 			Name:        "GLM-4V",
 			Description: "Vision model for image understanding",
 			Capabilities: []string{"vision", "image_analysis"},
-			Context:     8192,
+			Context:     zhipu.ContextWindow(zhipu.ModelGLM4V),
+		},
+		zhipu.ModelGLM4VPlus: {
+			ID:          zhipu.ModelGLM4VPlus,
+			Name:        "GLM-4V-Plus",
+			Description: "Vision model for images and video",
+			Capabilities: []string{"vision", "image_analysis"},
+			Context:     zhipu.ContextWindow(zhipu.ModelGLM4VPlus),
+		},
+		zhipu.ModelGLM4VThinking: {
+			ID:          zhipu.ModelGLM4VThinking,
+			Name:        "GLM-4.1V-Thinking",
+			Description: "Vision with deep thinking",
+			Capabilities: []string{"vision", "image_analysis", "reasoning"},
+			Context:     zhipu.ContextWindow(zhipu.ModelGLM4VThinking),
 		},
 		zhipu.ModelCodeGeeX4: {
 			ID:          zhipu.ModelCodeGeeX4,
 			Name:        "CodeGeeX-4",
 			Description: "Specialized code completion and generation",
 			Capabilities: []string{"code", "completion"},
-			Context:     32000,
+			Context:     zhipu.ContextWindow(zhipu.ModelCodeGeeX4),
 		},
 	}
 
 	if info, ok := models[modelID]; ok {
+		info.Provider = DefaultProvider
 		return info
 	}
-	return nil
+	return catalogueModel(modelID)
 }
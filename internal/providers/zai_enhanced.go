@@ -12,6 +12,8 @@ import (
 )
 
 // EnhancedZAIProvider extends ZAIProvider with advanced streaming and tool calling
+//
+// Deprecated: use ZAI, whose ChatStream returns the typed zhipu.Stream events.
 type EnhancedZAIProvider struct {
 	*ZAIProvider
 	toolRegistry map[string]Tool
@@ -60,8 +62,10 @@ type ToolCallEvent struct {
 type StreamHandler func(event StreamEvent)
 
 // NewEnhancedZAIProvider creates an enhanced provider
-func NewEnhancedZAIProvider(apiKey string) *EnhancedZAIProvider {
-	base := NewZAIProvider(apiKey)
+//
+// Deprecated: use NewZAI.
+func NewEnhancedZAIProvider(apiKey string, opts ...zhipu.Option) *EnhancedZAIProvider {
+	base := NewZAIProvider(apiKey, opts...)
 	return &EnhancedZAIProvider{
 		ZAIProvider:  base,
 		toolRegistry: make(map[string]Tool),
@@ -106,80 +110,105 @@ func (p *EnhancedZAIProvider) chatStreamInternal(ctx context.Context, input stri
 	messages := append(p.history, zhipu.Message{Role: "user", Content: input})
 
 	// Start the chat stream
-	textCh, errCh := p.client.ChatStream(ctx, &zhipu.ChatRequest{
+	stream, err := p.client.ChatStream(ctx, &zhipu.ChatRequest{
 		Model:       p.model,
 		Messages:    messages,
-		Temperature: p.temperature,
+		Temperature: zhipu.Float64(p.temperature),
 		Stream:      true,
 		Tools:       p.tools,
-		ToolChoice:  "auto",
+		ToolChoice:  zhipu.ToolChoiceAuto(),
 	})
+	if err != nil {
+		handler(StreamEvent{
+			Type:  EventError,
+			Error: err.Error(),
+		})
+		return err
+	}
+	defer stream.Close()
 
 	// Collect streaming text
 	var textBuilder strings.Builder
-	var currentToolCall *zhipu.ToolCall
-
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				// Stream ended - process any pending tool calls
-				if currentToolCall != nil {
-					if err := p.executeToolCall(ctx, currentToolCall, handler); err != nil {
-						handler(StreamEvent{
-							Type:  EventError,
-							Error: err.Error(),
-						})
-						return err
-					}
-				}
-
-				// Save to history
-				fullResponse := textBuilder.String()
-				if fullResponse != "" {
-					p.history = append(messages, zhipu.Message{Role: "assistant", Content: fullResponse})
-				}
-
-				// Send done event
-				handler(StreamEvent{
-					Type: EventDone,
-					Metadata: map[string]interface{}{
-						"tokens_used": len(strings.Split(fullResponse, " ")),
-					},
-				})
-				return nil
-			}
+	var toolCalls []zhipu.ToolCall
 
+	for stream.Next() {
+		ev := stream.Event()
+		switch ev.Type {
+		case zhipu.EventTextDelta:
 			// Send text event
 			handler(StreamEvent{
 				Type:    EventText,
-				Content: text,
+				Content: ev.Text,
 			})
 
-			textBuilder.WriteString(text)
-			p.streamBuffer.WriteString(text)
+			textBuilder.WriteString(ev.Text)
+			p.streamBuffer.WriteString(ev.Text)
 
-		case err := <-errCh:
-			if err != nil {
-				handler(StreamEvent{
-					Type:  EventError,
-					Error: err.Error(),
-				})
-				return err
-			}
+		case zhipu.EventToolCall:
+			toolCalls = append(toolCalls, *ev.ToolCall)
+
+		case zhipu.EventError:
+			handler(StreamEvent{
+				Type:  EventError,
+				Error: ev.Err.Error(),
+			})
+			return ev.Err
+		}
+	}
 
-		case <-ctx.Done():
-			return ctx.Err()
+	// Save to history
+	fullResponse := textBuilder.String()
+	if fullResponse != "" || len(toolCalls) > 0 {
+		msg := zhipu.Message{Role: "assistant", Content: fullResponse}
+		if len(toolCalls) > 0 {
+			msg.ToolCalls = toolCalls
 		}
+		p.history = append(messages, msg)
+	}
+
+	// Stream ended - process tool calls
+	for i := range toolCalls {
+		result, err := p.executeToolCall(ctx, &toolCalls[i], handler)
+		if err != nil {
+			handler(StreamEvent{
+				Type:  EventError,
+				Error: err.Error(),
+			})
+			return err
+		}
+		p.history = append(p.history, zhipu.Message{
+			Role:       "tool",
+			ToolCallID: toolCalls[i].ID,
+			Content:    result,
+		})
+	}
+
+	// Send done event
+	handler(StreamEvent{
+		Type: EventDone,
+		Metadata: usageMetadata(stream.Usage()),
+	})
+	return nil
+}
+
+// usageMetadata reports the token counts of a finished stream
+func usageMetadata(usage *zhipu.Usage) map[string]interface{} {
+	if usage == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"prompt_tokens":     usage.PromptTokens,
+		"completion_tokens": usage.CompletionTokens,
+		"total_tokens":      usage.TotalTokens,
 	}
 }
 
 // executeToolCall executes a tool call and streams the result
-func (p *EnhancedZAIProvider) executeToolCall(ctx context.Context, toolCall *zhipu.ToolCall, handler StreamHandler) error {
+func (p *EnhancedZAIProvider) executeToolCall(ctx context.Context, toolCall *zhipu.ToolCall, handler StreamHandler) (string, error) {
 	// Parse arguments
 	var args map[string]interface{}
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-		return fmt.Errorf("invalid tool arguments: %w", err)
+		return "", fmt.Errorf("invalid tool arguments: %w", err)
 	}
 
 	// Send tool call event
@@ -195,7 +224,7 @@ func (p *EnhancedZAIProvider) executeToolCall(ctx context.Context, toolCall *zhi
 	// Execute tool
 	tool, ok := p.toolRegistry[toolCall.Function.Name]
 	if !ok {
-		return fmt.Errorf("unknown tool: %s", toolCall.Function.Name)
+		return "", fmt.Errorf("unknown tool: %s", toolCall.Function.Name)
 	}
 
 	// Create timeout context
@@ -217,7 +246,7 @@ func (p *EnhancedZAIProvider) executeToolCall(ctx context.Context, toolCall *zhi
 		},
 	})
 
-	return nil
+	return result, nil
 }
 
 // buildToolSystemPrompt creates a system prompt describing available tools
@@ -2,8 +2,12 @@ package providers
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/biodoia/golem/pkg/zhipu"
 )
 
 func TestEnhancedZAIProvider_RegisterTool(t *testing.T) {
@@ -74,6 +78,34 @@ func TestEnhancedZAIProvider_StreamEvents(t *testing.T) {
 	}
 }
 
+func TestEnhancedZAIProvider_DoneUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"one two three"},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewEnhancedZAIProvider("test-key", zhipu.WithBaseURL(server.URL))
+
+	var done StreamEvent
+	err := provider.ChatStreamWithTools(context.Background(), "count", func(event StreamEvent) {
+		if event.Type == EventDone {
+			done = event
+		}
+	})
+	if err != nil {
+		t.Fatalf("ChatStreamWithTools failed: %v", err)
+	}
+
+	want := map[string]int{"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}
+	for key, n := range want {
+		if done.Metadata[key] != n {
+			t.Errorf("metadata[%s] = %v, want %d", key, done.Metadata[key], n)
+		}
+	}
+}
+
 func TestDefaultTools(t *testing.T) {
 	tools := DefaultTools()
 
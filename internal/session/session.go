@@ -20,12 +20,26 @@ const (
 
 // Session represents a chat session
 type Session struct {
-	ID        string          `json:"id"`
-	Name      string          `json:"name"`
-	Model     string          `json:"model"`
-	Messages  []zhipu.Message `json:"messages"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Model          string          `json:"model"`
+	Messages       []zhipu.Message `json:"messages"`
+	Usage          zhipu.Usage     `json:"usage"`                     // Tokens spent over all turns
+	KnowledgeID    string          `json:"knowledge_id,omitempty"`    // Retrieval source, set with /kb
+	AgentID        string          `json:"agent_id,omitempty"`        // Hosted agent, set with /zagent
+	ConversationID string          `json:"conversation_id,omitempty"` // Hosted agent conversation
+	Routes         []Route         `json:"routes,omitempty"`          // Requests sent to another model
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// Route records a request the router sent to another model than the
+// session's, e.g. a vision model for an image or a fallback
+type Route struct {
+	Time      time.Time `json:"time"`
+	Requested string    `json:"requested"`
+	Model     string    `json:"model"`
+	Reason    string    `json:"reason"`
 }
 
 // SessionManager manages chat sessions
@@ -124,16 +138,18 @@ func (sm *SessionManager) SetCurrent(session *Session) {
 
 // AddMessage adds a message to the current session and triggers auto-save if needed
 func (sm *SessionManager) AddMessage(role string, content string) bool {
+	return sm.AppendMessage(zhipu.Message{Role: role, Content: content})
+}
+
+// AppendMessage is AddMessage for a prebuilt message, e.g. one with images
+func (sm *SessionManager) AppendMessage(msg zhipu.Message) bool {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	if sm.current == nil {
 		sm.createSessionLocked("New Session", "glm-4-32b-0414")
 	}
-	sm.current.Messages = append(sm.current.Messages, zhipu.Message{
-		Role:    role,
-		Content: content,
-	})
+	sm.current.Messages = append(sm.current.Messages, msg)
 	sm.current.UpdatedAt = time.Now()
 	sm.messageCount++
 
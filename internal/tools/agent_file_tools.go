@@ -77,7 +77,7 @@ func AgentReadFile(argsJSON string) (string, error) {
 	}
 
 	if result.ReadError != "" {
-		return "", fmt.Errorf(result.ReadError)
+		return "", fmt.Errorf("%s", result.ReadError)
 	}
 
 	if !result.Exists {
@@ -186,7 +186,7 @@ func AgentWriteFile(argsJSON string) (string, error) {
 	}
 
 	if result.WriteError != "" {
-		return "", fmt.Errorf(result.WriteError)
+		return "", fmt.Errorf("%s", result.WriteError)
 	}
 
 	return fmt.Sprintf("Successfully wrote %d bytes to %s", result.Written, params.Path), nil
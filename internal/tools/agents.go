@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/golem/internal/agents"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// AgentsRunCommand runs one of the specialized agents on a task. The reply is
+// streamed, with its length shown as progress, and returned with the
+// tokens the run used.
+func AgentsRunCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Provider == nil {
+		return "", fmt.Errorf("/agents run needs a provider")
+	}
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: /agents run <type> <task>")
+	}
+	coordinator := agents.NewCoordinator(env.Provider)
+	agentType := agents.AgentType(args[0])
+	agent, ok := coordinator.GetAgent(agentType)
+	if !ok {
+		return "", fmt.Errorf("unknown agent: %s", args[0])
+	}
+	if env.Client == nil {
+		// The agents are tuned for GLM models; elsewhere use the chat model
+		agent.Model = env.Model
+	}
+
+	written := 0
+	result, err := coordinator.RunStream(ctx, agentType, agents.Task{Description: strings.Join(args[1:], " ")}, func(ev zhipu.StreamEvent) {
+		if ev.Type == zhipu.EventTextDelta {
+			written += len(ev.Text)
+			env.progress(fmt.Sprintf("%s is writing (%d bytes)", agent.Name, written))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n\n[%s, %d tokens]", result.Content, agent.Name, result.Tokens), nil
+}
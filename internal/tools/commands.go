@@ -31,6 +31,11 @@ func Commands() map[string]*Command {
 		cmdMCP,
 		cmdConfig,
 		cmdAuth,
+		cmdKB,
+		cmdImage,
+		cmdZAgent,
+		cmdSearch,
+		cmdResearch,
 		cmdClear,
 		cmdExit,
 		// File operations
@@ -78,10 +83,15 @@ var cmdHelp = &Command{
 		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "exists", "Check if file exists"))
 		b.WriteString("\nManagement:\n")
 		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "agents", "Manage specialized agents"))
-		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "model", "Switch or list models"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "model", "Switch, list or pull models"))
 		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "mcp", "Manage MCP servers"))
 		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "config", "View or edit configuration"))
 		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "auth", "Authentication management"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "kb", "Answer from a knowledge base of project docs"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "image", "Generate images with CogView"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "zagent", "Chat with a Z.AI hosted agent"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "search", "Search the web and add results to the context"))
+		b.WriteString(fmt.Sprintf("  /%-12s - %s\n", "research", "Research a question with live search and a cited report"))
 		return b.String(), nil
 	},
 }
@@ -165,28 +175,10 @@ var cmdAgents = &Command{
 
 Usage: /agents run <type> <task>`, nil
 		}
-		return "", fmt.Errorf("unknown subcommand: %s", args[0])
-	},
-}
-
-var cmdModel = &Command{
-	Name:        "model",
-	Aliases:     []string{"m"},
-	Description: "Switch or list models",
-	Usage:       "/model [list|<model-name>]",
-	Handler: func(ctx context.Context, args []string) (string, error) {
-		if len(args) == 0 || args[0] == "list" {
-			return `Available Z.AI models:
-  glm-4-32b-0414      - Dialogue, code, function calling (default)
-  glm-z1-32b-0414     - Deep thinking, math, reasoning
-  glm-z1-rumination   - Research, search-augmented
-  glm-z1-9b-0414      - Lightweight, efficient
-  glm-4.1v-9b-thinking - Vision + reasoning
-  codegeex-4          - Code completion
-
-Usage: /model <model-name>`, nil
+		if args[0] == "run" {
+			return AgentsRunCommand(ctx, args[1:])
 		}
-		return fmt.Sprintf("Switched to model: %s", args[0]), nil
+		return "", fmt.Errorf("unknown subcommand: %s", args[0])
 	},
 }
 
@@ -355,7 +347,7 @@ func ReadCommand(ctx context.Context, args []string) (string, error) {
 		return "", err
 	}
 	if result.ReadError != "" {
-		return "", fmt.Errorf(result.ReadError)
+		return "", fmt.Errorf("%s", result.ReadError)
 	}
 	if !result.Exists {
 		return "", fmt.Errorf("file does not exist: %s", args[0])
@@ -377,7 +369,7 @@ func WriteCommand(ctx context.Context, args []string) (string, error) {
 		return "", err
 	}
 	if result.WriteError != "" {
-		return "", fmt.Errorf(result.WriteError)
+		return "", fmt.Errorf("%s", result.WriteError)
 	}
 	return fmt.Sprintf("Wrote %d bytes to %s", result.Written, result.Path), nil
 }
@@ -392,7 +384,7 @@ func EditCommand(ctx context.Context, args []string) (string, error) {
 		return "", err
 	}
 	if result.EditError != "" {
-		return "", fmt.Errorf(result.EditError)
+		return "", fmt.Errorf("%s", result.EditError)
 	}
 	return fmt.Sprintf("Made %d replacements in %s", result.Replacements, result.Path), nil
 }
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/internal/providers"
+	"github.com/biodoia/golem/internal/session"
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// Env carries the app state that some commands act on
+type Env struct {
+	Settings config.Settings
+	Provider providers.Provider
+	Client   *zhipu.Client // Set when Provider is Z.AI, for Z.AI-only APIs
+	Session  *session.Session
+	Sessions *session.SessionManager
+
+	// Model is the chat model; a command may change it
+	Model string
+
+	// Progress, when set, shows a status line while a long command runs
+	Progress func(status string)
+}
+
+type envKey struct{}
+
+// WithEnv attaches env to ctx for command handlers
+func WithEnv(ctx context.Context, env *Env) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFrom returns the Env attached to ctx, or nil
+func EnvFrom(ctx context.Context) *Env {
+	env, _ := ctx.Value(envKey{}).(*Env)
+	return env
+}
+
+// progress reports status through env.Progress, if set
+func (env *Env) progress(status string) {
+	if env.Progress != nil {
+		env.Progress(status)
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+const imageUsage = "/image <prompt> [--out file.png] [--size WxH] [--quality standard|hd] [--n count]"
+
+var cmdImage = &Command{
+	Name:        "image",
+	Aliases:     []string{"img"},
+	Description: "Generate images with CogView",
+	Usage:       imageUsage,
+	Handler:     ImageCommand,
+}
+
+// imageOptions are the flags /image accepts anywhere among the prompt words
+type imageOptions struct {
+	prompt  string
+	out     string
+	size    string
+	quality string
+	n       int
+}
+
+func parseImageArgs(args []string) (imageOptions, error) {
+	opts := imageOptions{n: 1}
+	words, flags, err := splitFlags(args, "out", "size", "quality", "n")
+	if err != nil {
+		return opts, err
+	}
+	if n, ok := flags["n"]; ok {
+		if opts.n, err = strconv.Atoi(n); err != nil || opts.n < 1 || opts.n > 4 {
+			return opts, fmt.Errorf("--n must be between 1 and 4")
+		}
+	}
+	opts.out, opts.size, opts.quality = flags["out"], flags["size"], flags["quality"]
+	opts.prompt = strings.Join(words, " ")
+	if opts.prompt == "" {
+		return opts, fmt.Errorf("usage: %s", imageUsage)
+	}
+	return opts, nil
+}
+
+// ImageCommand generates images from a prompt and saves them to disk
+func ImageCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Client == nil {
+		return "", fmt.Errorf("/image needs the Z.AI provider")
+	}
+	opts, err := parseImageArgs(args)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := env.Client.ImageGenerate(ctx, &zhipu.ImageRequest{
+		Model:   zhipu.ModelCogView4,
+		Prompt:  opts.prompt,
+		Size:    opts.size,
+		Quality: opts.quality,
+		N:       opts.n,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("no image returned (the prompt may have been filtered)")
+	}
+
+	paths := imagePaths(opts.out, len(resp.Data))
+	var b strings.Builder
+	for i, img := range resp.Data {
+		if err := saveImage(ctx, env.Client, img, paths[i]); err != nil {
+			return b.String(), err
+		}
+		fmt.Fprintf(&b, "Saved %s\n", paths[i])
+	}
+	return b.String(), nil
+}
+
+// imagePaths numbers the output file when several images are generated;
+// without --out, files are named after the current time
+func imagePaths(out string, n int) []string {
+	if out == "" {
+		out = "image-" + time.Now().Format("20060102-150405") + ".png"
+	}
+	if n == 1 {
+		return []string{out}
+	}
+	ext := filepath.Ext(out)
+	base := strings.TrimSuffix(out, ext)
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s-%d%s", base, i+1, ext)
+	}
+	return paths
+}
+
+func saveImage(ctx context.Context, client *zhipu.Client, img zhipu.Image, path string) error {
+	body, err := client.DownloadImage(ctx, img)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	return f.Close()
+}
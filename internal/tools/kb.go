@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+// defaultKBGlobs are indexed when /kb index gets no patterns
+var defaultKBGlobs = []string{"docs/*.md", "*.md"}
+
+var cmdKB = &Command{
+	Name:        "kb",
+	Aliases:     []string{"knowledge"},
+	Description: "Answer from a knowledge base of project docs",
+	Usage:       "/kb [create <name> [description]|index [globs...]|use <id>|off|list|delete <id>]",
+	Handler:     KBCommand,
+}
+
+// KBCommand manages knowledge bases and the one the current session
+// retrieves from
+func KBCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Client == nil {
+		return "", fmt.Errorf("/kb needs the Z.AI provider")
+	}
+	if len(args) == 0 {
+		return kbStatus(env), nil
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: /kb create <name> [description]")
+		}
+		kb, err := env.Client.CreateKnowledge(ctx, &zhipu.CreateKnowledgeRequest{
+			Name:        args[1],
+			Description: strings.Join(args[2:], " "),
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := env.useKnowledge(kb.ID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created knowledge base %s (%s) and enabled it for this session.\nAdd documents with /kb index", kb.Name, kb.ID), nil
+	case "index":
+		if env.Session == nil || env.Session.KnowledgeID == "" {
+			return "", fmt.Errorf("no knowledge base selected. Use /kb create or /kb use first")
+		}
+		globs := args[1:]
+		if len(globs) == 0 {
+			globs = defaultKBGlobs
+		}
+		return kbIndex(ctx, env, globs)
+	case "use":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: /kb use <id>")
+		}
+		if err := env.useKnowledge(args[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Retrieval enabled from knowledge base %s", args[1]), nil
+	case "off":
+		if err := env.useKnowledge(""); err != nil {
+			return "", err
+		}
+		return "Retrieval disabled for this session", nil
+	case "list":
+		list, total, err := env.Client.ListKnowledge(ctx, 1, 50)
+		if err != nil {
+			return "", err
+		}
+		if total == 0 {
+			return "No knowledge bases. Create one with /kb create <name>", nil
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Knowledge bases (%d):\n", total)
+		for _, kb := range list {
+			fmt.Fprintf(&b, "  %-20s %-24s %d docs\n", kb.ID, kb.Name, kb.DocumentSize)
+		}
+		return b.String(), nil
+	case "delete":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: /kb delete <id>")
+		}
+		if err := env.Client.DeleteKnowledge(ctx, args[1]); err != nil {
+			return "", err
+		}
+		if env.Session != nil && env.Session.KnowledgeID == args[1] {
+			if err := env.useKnowledge(""); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("Deleted knowledge base %s", args[1]), nil
+	}
+	return "", fmt.Errorf("unknown kb command: %s", args[0])
+}
+
+func kbStatus(env *Env) string {
+	if env.Session == nil || env.Session.KnowledgeID == "" {
+		return "Retrieval is off. Use /kb create <name> or /kb use <id>"
+	}
+	return fmt.Sprintf("Retrieval is on, from knowledge base %s", env.Session.KnowledgeID)
+}
+
+// kbIndex uploads every file matching globs to the session's knowledge base
+func kbIndex(ctx context.Context, env *Env, globs []string) (string, error) {
+	seen := make(map[string]bool)
+	var docs []zhipu.DocumentUpload
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for _, pattern := range globs {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			if info, err := f.Stat(); err != nil || info.IsDir() {
+				f.Close()
+				continue
+			}
+			files = append(files, f)
+			docs = append(docs, zhipu.DocumentUpload{Filename: filepath.Base(path), Content: f})
+		}
+	}
+	if len(docs) == 0 {
+		return "", fmt.Errorf("no files match %s", strings.Join(globs, " "))
+	}
+
+	result, err := env.Client.UploadDocuments(ctx, env.Session.KnowledgeID, docs...)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Indexed %d of %d files into %s", len(result.Succeeded), len(docs), env.Session.KnowledgeID)
+	for _, f := range result.Failed {
+		fmt.Fprintf(&b, "\n  %s: %s", f.Filename, f.Reason)
+	}
+	b.WriteString("\nEmbedding runs in the background; new documents are searchable in a few minutes.")
+	return b.String(), nil
+}
+
+// useKnowledge sets the session's knowledge base; empty turns retrieval off
+func (env *Env) useKnowledge(id string) error {
+	if env.Session == nil {
+		return fmt.Errorf("no active session")
+	}
+	env.Session.KnowledgeID = id
+	if env.Sessions != nil {
+		return env.Sessions.Save(env.Session)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biodoia/golem/internal/providers"
+)
+
+var cmdModel = &Command{
+	Name:        "model",
+	Aliases:     []string{"m"},
+	Description: "Switch, list or pull models",
+	Usage:       "/model [list|pull <name>|<model-name>]",
+	Handler:     ModelCommand,
+}
+
+// ollamaListTimeout keeps /model list quick when no Ollama server runs
+const ollamaListTimeout = 2 * time.Second
+
+// ModelCommand lists the available models, downloads Ollama models or
+// switches the chat model
+func ModelCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil {
+		env = &Env{}
+	}
+	if len(args) == 0 || args[0] == "list" {
+		return modelList(ctx, env), nil
+	}
+	if args[0] == "pull" {
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: /model pull <name>, e.g. /model pull llama3.2")
+		}
+		return modelPull(ctx, env, strings.TrimPrefix(args[1], "ollama/"))
+	}
+
+	env.Model = args[0]
+	if env.Session != nil {
+		env.Session.Model = args[0]
+		if env.Sessions != nil {
+			if err := env.Sessions.Save(env.Session); err != nil {
+				return "", err
+			}
+		}
+	}
+	return fmt.Sprintf("Switched to model: %s", args[0]), nil
+}
+
+// modelList shows the Z.AI models, the models installed in Ollama, the
+// catalogue of the other hosted vendors and, for any other active
+// provider, the models it serves
+func modelList(ctx context.Context, env *Env) string {
+	var b strings.Builder
+	writeModels := func(prefix string, models []providers.ModelInfo) {
+		for _, m := range models {
+			mark := " "
+			if prefix+m.ID == env.Model {
+				mark = "*"
+			}
+			fmt.Fprintf(&b, "  %s %-28s %s\n", mark, prefix+m.ID, m.Description)
+		}
+	}
+
+	b.WriteString("Z.AI models:\n")
+	writeModels("", providers.ZAIModels())
+
+	b.WriteString("\nOllama models (local):\n")
+	if ollama, err := env.ollama(); err != nil {
+		fmt.Fprintf(&b, "  %v\n", err)
+	} else {
+		listCtx, cancel := context.WithTimeout(ctx, ollamaListTimeout)
+		models, err := ollama.Models(listCtx)
+		cancel()
+		switch {
+		case err != nil:
+			b.WriteString("  Ollama is not running. Start it with: ollama serve\n")
+		case len(models) == 0:
+			b.WriteString("  None installed. Download one with /model pull <name>\n")
+		default:
+			writeModels("ollama/", models)
+		}
+	}
+
+	// Hosted vendors, switched to with /model <provider>/<id>
+	var vendors []string
+	for _, m := range providers.Catalogue("") {
+		if len(vendors) == 0 || vendors[len(vendors)-1] != m.Provider {
+			vendors = append(vendors, m.Provider)
+		}
+	}
+	for _, vendor := range vendors {
+		fmt.Fprintf(&b, "\n%s models:\n", vendor)
+		writeModels(vendor+"/", providers.Catalogue(vendor))
+	}
+
+	if p := env.Provider; p != nil && p.Name() != providers.DefaultProvider {
+		if _, isOllama := p.(*providers.Ollama); !isOllama && len(providers.Catalogue(p.Name())) == 0 {
+			fmt.Fprintf(&b, "\n%s models:\n", p.Name())
+			if models, err := p.Models(ctx); err != nil {
+				fmt.Fprintf(&b, "  %v\n", err)
+			} else {
+				writeModels("", models)
+			}
+		}
+	}
+
+	b.WriteString("\nUsage: /model <name> | /model pull <name>")
+	return b.String()
+}
+
+// modelPull downloads a model into Ollama, reporting progress as it goes
+func modelPull(ctx context.Context, env *Env, name string) (string, error) {
+	ollama, err := env.ollama()
+	if err != nil {
+		return "", err
+	}
+	err = ollama.Pull(ctx, name, func(pp providers.PullProgress) {
+		env.progress("Pulling " + name + ": " + pp.String())
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Pulled %s. Switch to it with /model ollama/%s", name, name), nil
+}
+
+// ollama returns the active provider if it is Ollama, or a new one from
+// the settings
+func (env *Env) ollama() (*providers.Ollama, error) {
+	if p, ok := env.Provider.(*providers.Ollama); ok {
+		return p, nil
+	}
+	settings := env.Settings
+	settings.Provider = "ollama"
+	p, err := providers.New(settings)
+	if err != nil {
+		return nil, err
+	}
+	ollama, ok := p.(*providers.Ollama)
+	if !ok {
+		return nil, fmt.Errorf("provider %q is not Ollama", settings.Provider)
+	}
+	return ollama, nil
+}
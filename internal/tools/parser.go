@@ -1,6 +1,11 @@
 package tools
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
 
 // ParseCommand returns cmd and args when input starts with /.
 func ParseCommand(input string) (string, []string, bool) {
@@ -14,3 +19,65 @@ func ParseCommand(input string) (string, []string, bool) {
 	}
 	return parts[0], parts[1:], true
 }
+
+// imagePrefix marks an image attachment in chat input
+const imagePrefix = "@image:"
+
+// imageToken matches an @image:path word; group 1 is the path
+var imageToken = regexp.MustCompile(`(?:^|\s)` + imagePrefix + `(\S+)`)
+
+// ParseAttachments removes @image:path tokens from input and returns the
+// remaining text, with its line breaks intact, and the image paths in order.
+// Tokens written without a space between them, as in
+// "@image:a.png@image:b.png", are separate attachments.
+func ParseAttachments(input string) (string, []string) {
+	if !strings.Contains(input, imagePrefix) {
+		return input, nil
+	}
+	var images []string
+	var text strings.Builder
+	last := 0
+	for _, m := range imageToken.FindAllStringSubmatchIndex(input, -1) {
+		for _, path := range strings.Split(input[m[2]:m[3]], imagePrefix) {
+			if path != "" {
+				images = append(images, expandHome(path))
+			}
+		}
+		// Drop the token and the blanks before it, or after it when it
+		// starts a line
+		text.WriteString(strings.TrimRight(input[last:m[2]-len(imagePrefix)], " \t"))
+		last = m[3]
+		if written := text.String(); written == "" || strings.HasSuffix(written, "\n") {
+			last = len(input) - len(strings.TrimLeft(input[last:], " \t"))
+		}
+	}
+	text.WriteString(input[last:])
+	return strings.TrimSpace(text.String()), images
+}
+
+// splitFlags separates --name value and --name=value flags, which may appear
+// anywhere among the words of a command, from the other words
+func splitFlags(args []string, names ...string) ([]string, map[string]string, error) {
+	var words []string
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg, ok := strings.CutPrefix(args[i], "--")
+		if !ok {
+			words = append(words, args[i])
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !slices.Contains(names, name) {
+			return nil, nil, fmt.Errorf("unknown flag: --%s", name)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+	return words, flags, nil
+}
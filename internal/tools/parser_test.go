@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseAttachments(t *testing.T) {
+	t.Setenv("HOME", "/home/golem")
+
+	tests := []struct {
+		name   string
+		input  string
+		text   string
+		images []string
+	}{
+		{"no attachment", "hello\nworld", "hello\nworld", nil},
+		{"start of input", "@image:a.png what is this?", "what is this?", []string{"a.png"}},
+		{"middle of line", "compare @image:a.png with this", "compare with this", []string{"a.png"}},
+		{"end of line", "what is this? @image:a.png", "what is this?", []string{"a.png"}},
+		{"multi-line", "first line @image:a.png\nsecond line\n@image:b.png third line", "first line\nsecond line\nthird line", []string{"a.png", "b.png"}},
+		{"home", "look @image:~/shots/a.png", "look", []string{"/home/golem/shots/a.png"}},
+		{"adjacent", "@image:a.png@image:b.png which is newer?", "which is newer?", []string{"a.png", "b.png"}},
+		{"not a token", "mail me@image:a.png", "mail me@image:a.png", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, images := ParseAttachments(tt.input)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if !slices.Equal(images, tt.images) {
+				t.Errorf("images = %q, want %q", images, tt.images)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/biodoia/golem/internal/agents"
+)
+
+var cmdResearch = &Command{
+	Name:        "research",
+	Aliases:     []string{},
+	Description: "Research a question on the web with the rumination model",
+	Usage:       "/research <question>",
+	Handler:     ResearchCommand,
+}
+
+// ResearchCommand runs a research session and returns its markdown report.
+// The TUI streams the session instead, showing reasoning and sources live.
+func ResearchCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Client == nil {
+		return "", fmt.Errorf("/research needs the Z.AI provider")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /research <question>")
+	}
+	report, err := agents.NewCoordinator(env.Provider).Research(ctx, strings.Join(args, " "), nil)
+	if err != nil {
+		return "", err
+	}
+	return report.Markdown(), nil
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/biodoia/golem/pkg/zhipu"
+)
+
+const searchUsage = "/search <query> [--domain site] [--recency oneDay|oneWeek|oneMonth|oneYear] [--n count] | /search add <n>..."
+
+var cmdSearch = &Command{
+	Name:        "search",
+	Aliases:     []string{"web"},
+	Description: "Search the web and add results to the context",
+	Usage:       searchUsage,
+	Handler:     SearchCommand,
+}
+
+// lastSearch holds the results of the previous /search for /search add
+var (
+	lastSearch   []zhipu.WebSearchResult
+	lastSearchMu sync.Mutex
+)
+
+// SearchCommand shows web search results, or adds chosen results from the
+// previous search to the conversation
+func SearchCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Client == nil {
+		return "", fmt.Errorf("/search needs the Z.AI provider")
+	}
+	if len(args) > 0 && args[0] == "add" {
+		return searchAdd(env, args[1:])
+	}
+
+	words, flags, err := splitFlags(args, "domain", "recency", "n")
+	if err != nil {
+		return "", err
+	}
+	if len(words) == 0 {
+		return "", fmt.Errorf("usage: %s", searchUsage)
+	}
+	count := 5
+	if v, ok := flags["n"]; ok {
+		if count, err = strconv.Atoi(v); err != nil || count < 1 || count > 50 {
+			return "", fmt.Errorf("--n must be between 1 and 50")
+		}
+	}
+
+	resp, err := env.Client.WebSearch(ctx, &zhipu.WebSearchRequest{
+		SearchQuery:         strings.Join(words, " "),
+		Count:               count,
+		SearchDomainFilter:  flags["domain"],
+		SearchRecencyFilter: flags["recency"],
+	})
+	if err != nil {
+		return "", err
+	}
+
+	lastSearchMu.Lock()
+	lastSearch = resp.SearchResult
+	lastSearchMu.Unlock()
+
+	out := zhipu.FormatWebSearchResults(resp.SearchResult)
+	if len(resp.SearchResult) > 0 {
+		out += "\nAdd results to the context with /search add <n>..."
+	}
+	return out, nil
+}
+
+// searchAdd appends the chosen results, with their full text, to the
+// conversation as a user message
+func searchAdd(env *Env, args []string) (string, error) {
+	if env.Sessions == nil {
+		return "", fmt.Errorf("no active session")
+	}
+	lastSearchMu.Lock()
+	results := lastSearch
+	lastSearchMu.Unlock()
+	if len(results) == 0 {
+		return "", fmt.Errorf("no search results yet. Run /search <query> first")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /search add <n>... (1-%d)", len(results))
+	}
+
+	var chosen []zhipu.WebSearchResult
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(results) {
+			return "", fmt.Errorf("no result %s (1-%d)", arg, len(results))
+		}
+		chosen = append(chosen, results[n-1])
+	}
+
+	var b strings.Builder
+	b.WriteString("Web search results to use as sources. Cite them as [n] and list their links at the end of your answer.\n\n")
+	for i, r := range chosen {
+		fmt.Fprintf(&b, "[%d] %s\n%s\n%s\n\n", i+1, r.Title, r.Link, strings.TrimSpace(r.Content))
+	}
+	env.Sessions.AppendMessage(zhipu.Message{Role: "user", Content: b.String()})
+	return fmt.Sprintf("Added %d result(s) to the context", len(chosen)), nil
+}
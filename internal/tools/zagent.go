@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+var cmdZAgent = &Command{
+	Name:        "zagent",
+	Aliases:     []string{},
+	Description: "Chat with a Z.AI hosted agent instead of a model",
+	Usage:       "/zagent [<agent_id>|new|off]",
+	Handler:     ZAgentCommand,
+}
+
+// ZAgentCommand routes the current session to a hosted agent. The agent
+// keeps the conversation history server-side, keyed by its conversation ID.
+func ZAgentCommand(ctx context.Context, args []string) (string, error) {
+	env := EnvFrom(ctx)
+	if env == nil || env.Session == nil {
+		return "", fmt.Errorf("/zagent needs an active session")
+	}
+	s := env.Session
+	if len(args) == 0 {
+		if s.AgentID == "" {
+			return "Chatting with the model. Use /zagent <agent_id> to talk to a hosted agent", nil
+		}
+		return fmt.Sprintf("Chatting with agent %s (conversation %s)", s.AgentID, orNone(s.ConversationID)), nil
+	}
+
+	var out string
+	switch args[0] {
+	case "off":
+		s.AgentID, s.ConversationID = "", ""
+		out = "Back to chatting with the model"
+	case "new":
+		if s.AgentID == "" {
+			return "", fmt.Errorf("no agent selected")
+		}
+		s.ConversationID = ""
+		out = fmt.Sprintf("Started a new conversation with agent %s", s.AgentID)
+	default:
+		if args[0] != s.AgentID {
+			s.AgentID, s.ConversationID = args[0], ""
+		}
+		out = fmt.Sprintf("Messages now go to agent %s", s.AgentID)
+	}
+	if env.Sessions != nil {
+		if err := env.Sessions.Save(s); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none yet"
+	}
+	return s
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,7 +11,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/biodoia/golem/internal/agents"
 	"github.com/biodoia/golem/internal/config"
+	"github.com/biodoia/golem/internal/providers"
 	"github.com/biodoia/golem/internal/session"
 	"github.com/biodoia/golem/internal/tools"
 	"github.com/biodoia/golem/pkg/zhipu"
@@ -22,13 +25,19 @@ type Model struct {
 	height         int
 	loading        bool
 	model          string
-	apiKey         string
-	client         *zhipu.Client
+	settings       config.Settings
+	provider       providers.Provider
+	providerErr    error         // Why provider is nil
+	router         *providers.Router
+	routes         chan providers.RouteDecision
+	client         *zhipu.Client // Set when provider is Z.AI
 	cmds           map[string]*tools.Command
 	ready          bool
 	theme          lipgloss.Style
-	streamText     <-chan string
-	streamErr      <-chan error
+	stream         *zhipu.Stream
+	showReasoning  bool
+	lastUsage      *zhipu.Usage
+	research       *agents.ResearchReport // Set while /research streams
 	sessions       *session.SessionManager
 	currentSession *session.Session
 	statusMessage  string
@@ -40,16 +49,23 @@ type Message struct {
 	Time    time.Time
 }
 
-type responseMsg struct{ text string }
+type responseMsg struct {
+	text  string
+	model string // Chat model after the command, if it changed
+}
+
+// progressMsg shows a status update of a running command and waits for
+// the next one on ch
+type progressMsg struct {
+	text string
+	ch   <-chan string
+}
 
 type errorMsg struct{ err error }
 
-type streamingMsg struct{ text string }
+type streamingMsg struct{ event zhipu.StreamEvent }
 
-type startStreamMsg struct {
-	textCh <-chan string
-	errCh  <-chan error
-}
+type startStreamMsg struct{ stream *zhipu.Stream }
 
 type streamDoneMsg struct{}
 
@@ -59,8 +75,24 @@ type sessionMsg struct {
 
 type statusMsg struct{ text string }
 
+// routeMsg reports a request sent to another model and waits for the
+// next decision on ch
+type routeMsg struct {
+	decision providers.RouteDecision
+	ch       <-chan providers.RouteDecision
+}
+
 func NewAppModel(settings config.Settings) Model {
-	client := zhipu.NewClient(settings.APIKey)
+	provider, providerErr := providers.New(settings)
+	client, _ := providers.ZhipuClient(provider)
+	router := providers.NewRouter(settings, provider)
+	routes := make(chan providers.RouteDecision, 8)
+	router.OnDecision = func(d providers.RouteDecision) {
+		select {
+		case routes <- d:
+		default: // The UI is behind; drop the decision rather than block the request
+		}
+	}
 	cmds := tools.Commands()
 	extCmds := tools.LoadExternalCommands(config.CommandsSearchPaths(settings.CommandsPath))
 	for k, v := range extCmds {
@@ -75,7 +107,11 @@ func NewAppModel(settings config.Settings) Model {
 
 	return Model{
 		model:          settings.Model,
-		apiKey:         settings.APIKey,
+		settings:       settings,
+		provider:       provider,
+		providerErr:    providerErr,
+		router:         router,
+		routes:         routes,
 		client:         client,
 		cmds:           cmds,
 		theme:          lipgloss.NewStyle().Foreground(lipgloss.Color("#00ffff")),
@@ -85,7 +121,7 @@ func NewAppModel(settings config.Settings) Model {
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.EnterAltScreen
+	return tea.Batch(tea.EnterAltScreen, waitRoute(m.routes))
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -102,6 +138,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.sessions.Save(m.currentSession)
 			}
 			return m, tea.Quit
+		case "ctrl+t":
+			// Expand or collapse reasoning blocks
+			m.showReasoning = !m.showReasoning
 		case "enter":
 			if strings.TrimSpace(m.input) == "" || m.loading {
 				return m, nil
@@ -117,11 +156,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Handle tool commands (/...)
 			if cmd, args, isCmd := tools.ParseCommand(input); isCmd {
+				if cmd == "research" && len(args) > 0 {
+					return m.startResearch(strings.Join(args, " "))
+				}
 				return m, m.handleCommand(cmd, args)
 			}
 
-			// Regular message
-			m.addMessage("user", input)
+			// Regular message, with any @image:path attachments
+			text, images := tools.ParseAttachments(input)
+			if len(images) > 0 {
+				msg, err := zhipu.NewImageMessage(text, images...)
+				if err != nil {
+					m.addMessage("error", err.Error())
+					return m, nil
+				}
+				m.appendMessage(msg)
+				m.statusMessage = "Image attached"
+			} else {
+				m.addMessage("user", input)
+			}
 			m.loading = true
 			return m, m.sendMessage(input)
 		case "backspace":
@@ -135,34 +188,75 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case responseMsg:
 		m.loading = false
+		if msg.model != "" {
+			m.model = msg.model
+		}
 		m.addMessage("assistant", msg.text)
+	case progressMsg:
+		m.statusMessage = msg.text
+		return m, waitProgress(msg.ch)
+	case routeMsg:
+		m.statusMessage = msg.decision.String()
+		if m.currentSession != nil && msg.decision.Rerouted() {
+			m.currentSession.Routes = append(m.currentSession.Routes, session.Route{
+				Time:      time.Now(),
+				Requested: msg.decision.Requested,
+				Model:     msg.decision.Model,
+				Reason:    msg.decision.Reason,
+			})
+			m.sessions.Save(m.currentSession)
+		}
+		return m, waitRoute(msg.ch)
 	case startStreamMsg:
-		m.streamText = msg.textCh
-		m.streamErr = msg.errCh
+		m.stream = msg.stream
 		// Add empty assistant message to stream into
 		m.addMessage("assistant", "")
-		return m, streamNext(m.streamText, m.streamErr)
+		return m, streamNext(m.stream)
 	case streamingMsg:
 		if m.currentSession != nil && len(m.currentSession.Messages) > 0 {
 			lastIdx := len(m.currentSession.Messages) - 1
-			if content, ok := m.currentSession.Messages[lastIdx].Content.(string); ok {
-				m.currentSession.Messages[lastIdx].Content = content + msg.text
+			last := &m.currentSession.Messages[lastIdx]
+			if m.research != nil {
+				// Show each newly consulted page in the thinking trace
+				for _, src := range m.research.Add(msg.event) {
+					last.ReasoningContent += fmt.Sprintf("\n🔎 %s — %s\n", src.Title, src.Link)
+					m.statusMessage = "Reading: " + src.Title
+				}
+			}
+			switch msg.event.Type {
+			case zhipu.EventTextDelta:
+				if content, ok := last.Content.(string); ok {
+					last.Content = content + msg.event.Text
+				}
+			case zhipu.EventReasoningDelta:
+				last.ReasoningContent += msg.event.Text
+			case zhipu.EventUsage:
+				m.lastUsage = msg.event.Usage
+				m.currentSession.Usage.Add(*msg.event.Usage)
 			}
 		}
-		return m, streamNext(m.streamText, m.streamErr)
+		return m, streamNext(m.stream)
 	case streamDoneMsg:
 		m.loading = false
-		m.streamText = nil
-		m.streamErr = nil
+		if m.research != nil {
+			m.finishResearch()
+		}
+		if m.stream != nil && m.currentSession != nil && m.currentSession.AgentID != "" {
+			if id := m.stream.ConversationID(); id != "" {
+				m.currentSession.ConversationID = id
+			}
+		}
+		m.closeStream()
 		// Auto-save after stream completes
 		if m.currentSession != nil {
 			m.sessions.Save(m.currentSession)
 		}
 	case errorMsg:
 		m.loading = false
-		m.streamText = nil
-		m.streamErr = nil
+		m.research = nil
+		m.closeStream()
 		m.addMessage("error", msg.err.Error())
+		m.statusMessage = errorHint(msg.err)
 	case statusMsg:
 		m.statusMessage = msg.text
 	}
@@ -208,6 +302,7 @@ func (m Model) handleSessionCommand(input string) (bool, tea.Model, tea.Cmd) {
 			m.sessions.Save(m.currentSession)
 		}
 		m.currentSession = m.sessions.CreateSession(name, m.model)
+		m.lastUsage = nil
 		m.statusMessage = "New session: " + name
 		return true, m, nil
 
@@ -251,6 +346,7 @@ func (m Model) handleSessionCommand(input string) (bool, tea.Model, tea.Cmd) {
 			return true, m, nil
 		}
 		m.currentSession = sess
+		m.lastUsage = nil
 		m.sessions.SetCurrent(sess)
 		m.statusMessage = "Loaded: " + sess.Name
 		return true, m, nil
@@ -346,6 +442,20 @@ func (m Model) handleSessionCommand(input string) (bool, tea.Model, tea.Cmd) {
 		}
 		return true, m, nil
 
+	case "routes":
+		// :routes - show the routing log of the current session
+		if m.currentSession == nil || len(m.currentSession.Routes) == 0 {
+			m.statusMessage = "No requests were rerouted in this session"
+			return true, m, nil
+		}
+		var b strings.Builder
+		b.WriteString("Routed requests:\n")
+		for _, r := range m.currentSession.Routes {
+			b.WriteString(fmt.Sprintf("  %s  %s → %s (%s)\n", r.Time.Format("Jan 2 15:04"), r.Requested, r.Model, r.Reason))
+		}
+		m.addMessage("system", strings.TrimRight(b.String(), "\n"))
+		return true, m, nil
+
 	case "help", "h", "?":
 		// :help - show session commands
 		help := `Session Commands:
@@ -358,6 +468,7 @@ func (m Model) handleSessionCommand(input string) (bool, tea.Model, tea.Cmd) {
   :delete <id>       Delete a session
   :export <path>     Export session to file
   :import <path>     Import session from file
+  :routes            Show requests sent to another model
   :help              Show this help`
 		m.addMessage("system", help)
 		return true, m, nil
@@ -367,11 +478,15 @@ func (m Model) handleSessionCommand(input string) (bool, tea.Model, tea.Cmd) {
 }
 
 func (m Model) addMessage(role string, content string) {
+	m.appendMessage(zhipu.Message{Role: role, Content: content})
+}
+
+func (m Model) appendMessage(msg zhipu.Message) {
 	if m.currentSession == nil {
 		return
 	}
-	// Use session manager's AddMessage for proper auto-save tracking
-	shouldSave := m.sessions.AddMessage(role, content)
+	// Use session manager's AppendMessage for proper auto-save tracking
+	shouldSave := m.sessions.AppendMessage(msg)
 	if shouldSave {
 		// Auto-save triggered (every N messages)
 		m.sessions.Save(m.currentSession)
@@ -407,10 +522,14 @@ func (m Model) View() string {
 				prefix = "Error"
 				style = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555"))
 			}
-			content := msg.Content
-			if s, ok := content.(string); ok {
-				b.WriteString(style.Render(prefix+": ") + s + "\n\n")
+			if msg.ReasoningContent != "" {
+				b.WriteString(m.renderReasoning(msg.ReasoningContent))
+			}
+			text := msg.Text()
+			if n := msg.ImageCount(); n > 0 {
+				text = fmt.Sprintf("[%d image(s)] %s", n, text)
 			}
+			b.WriteString(style.Render(prefix+": ") + text + "\n\n")
 		}
 	}
 
@@ -424,7 +543,20 @@ func (m Model) View() string {
 	if m.statusMessage != "" {
 		statusParts = append(statusParts, m.statusMessage)
 	}
-	statusParts = append(statusParts, fmt.Sprintf("Model: %s", m.model))
+	if m.currentSession != nil && m.currentSession.AgentID != "" {
+		statusParts = append(statusParts, fmt.Sprintf("Agent: %s", m.currentSession.AgentID))
+	} else if m.provider != nil && m.provider.Name() != providers.DefaultProvider {
+		statusParts = append(statusParts, fmt.Sprintf("Model: %s/%s", m.provider.Name(), m.model))
+	} else {
+		statusParts = append(statusParts, fmt.Sprintf("Model: %s", m.model))
+	}
+	if m.lastUsage != nil {
+		tokens := fmt.Sprintf("Tokens: %d in / %d out", m.lastUsage.PromptTokens, m.lastUsage.CompletionTokens)
+		if m.currentSession != nil {
+			tokens += fmt.Sprintf(" (session %d)", m.currentSession.Usage.TotalTokens)
+		}
+		statusParts = append(statusParts, tokens)
+	}
 	statusParts = append(statusParts, ":help for commands")
 
 	status := " " + strings.Join(statusParts, " | ")
@@ -433,10 +565,43 @@ func (m Model) View() string {
 	return b.String()
 }
 
+// errorHint suggests what to do about an API error, if anything
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, zhipu.ErrAuth):
+		return "Invalid API key: set ZAI_API_KEY or use /auth login"
+	case errors.Is(err, zhipu.ErrQuotaExceeded):
+		return "Quota exhausted: top up your Z.AI balance"
+	case errors.Is(err, zhipu.ErrRateLimited):
+		return "Rate limited: wait a moment and retry"
+	case errors.Is(err, zhipu.ErrContextLength):
+		return "Conversation too long: start a new session with :n"
+	case errors.Is(err, zhipu.ErrContentFiltered):
+		return "Blocked by content filter"
+	}
+	return ""
+}
+
 func (m Model) handleCommand(cmd string, args []string) tea.Cmd {
 	if command, ok := m.cmds[cmd]; ok {
-		return func() tea.Msg {
-			output, err := command.Handler(context.Background(), args)
+		progress := make(chan string, 8)
+		run := func() tea.Msg {
+			defer close(progress)
+			env := &tools.Env{
+				Settings: m.settings,
+				Provider: m.provider,
+				Client:   m.client,
+				Session:  m.currentSession,
+				Sessions: m.sessions,
+				Model:    m.model,
+				Progress: func(status string) {
+					select {
+					case progress <- status:
+					default: // The UI is behind; a later update replaces this one
+					}
+				},
+			}
+			output, err := command.Handler(tools.WithEnv(context.Background(), env), args)
 			if err != nil {
 				return errorMsg{err: err}
 			}
@@ -444,8 +609,13 @@ func (m Model) handleCommand(cmd string, args []string) tea.Cmd {
 				return tea.Quit()
 			}
 			m.addMessage("user", "/"+cmd+" "+strings.Join(args, " "))
-			return responseMsg{text: output}
+			resp := responseMsg{text: output}
+			if env.Model != m.model {
+				resp.model = env.Model
+			}
+			return resp
 		}
+		return tea.Batch(run, waitProgress(progress))
 	}
 	return func() tea.Msg {
 		return errorMsg{err: fmt.Errorf("unknown command: %s", cmd)}
@@ -454,8 +624,12 @@ func (m Model) handleCommand(cmd string, args []string) tea.Cmd {
 
 func (m Model) sendMessage(input string) tea.Cmd {
 	return func() tea.Msg {
-		if m.apiKey == "" {
-			return errorMsg{err: fmt.Errorf("missing API key. Set ZAI_API_KEY or ZHIPU_API_KEY")}
+		provider, _, err := m.routeModel()
+		if err != nil {
+			return errorMsg{err: err}
+		}
+		if provider == nil {
+			return errorMsg{err: m.providerErr}
 		}
 		ctx := context.Background()
 
@@ -469,36 +643,140 @@ func (m Model) sendMessage(input string) tea.Cmd {
 			}
 		}
 
+		// A hosted agent keeps the history itself; send only the new message
+		if s := m.currentSession; s != nil && s.AgentID != "" && m.client != nil && len(messages) > 0 {
+			stream, err := m.client.AgentChatStream(ctx, &zhipu.AgentChatRequest{
+				AgentID:        s.AgentID,
+				Messages:       messages[len(messages)-1:],
+				ConversationID: s.ConversationID,
+			})
+			if err != nil {
+				return errorMsg{err: err}
+			}
+			return startStreamMsg{stream: stream}
+		}
+
+		if m.model == "" {
+			return errorMsg{err: fmt.Errorf("%w for provider %q: pick one with /model", config.ErrNoModel, m.settings.Provider)}
+		}
+
 		req := &zhipu.ChatRequest{
 			Model:    m.model,
 			Messages: messages,
 			Stream:   true,
 		}
-		textCh, errCh := m.client.ChatStream(ctx, req)
-		return startStreamMsg{textCh: textCh, errCh: errCh}
+		if m.currentSession != nil && m.currentSession.KnowledgeID != "" {
+			req.Tools = []zhipu.Tool{zhipu.NewRetrievalTool(m.currentSession.KnowledgeID, "")}
+		}
+
+		// The router leaves out the oldest turns rather than fail once the
+		// chat outgrows the window of the model it sends to, and reports
+		// that as a routing decision
+		stream, err := m.router.ChatStream(ctx, req)
+		if err != nil {
+			return errorMsg{err: err}
+		}
+		return startStreamMsg{stream: stream}
+	}
+}
+
+// routeModel returns the provider the chat model is sent to and the name
+// that provider knows it by; "ollama/llama3.2" goes to Ollama
+func (m Model) routeModel() (providers.Provider, string, error) {
+	return providers.ForModel(m.settings, m.provider, m.model)
+}
+
+// waitRoute waits for the next routing decision
+func waitRoute(ch <-chan providers.RouteDecision) tea.Cmd {
+	return func() tea.Msg {
+		d, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return routeMsg{decision: d, ch: ch}
+	}
+}
+
+// waitProgress waits for the next status update of a running command
+func waitProgress(ch <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		text, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return progressMsg{text: text, ch: ch}
+	}
+}
+
+// renderReasoning shows a model's thinking trace as a collapsible block
+func (m Model) renderReasoning(reasoning string) string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280")).Italic(true)
+	if !m.showReasoning {
+		return style.Render(fmt.Sprintf("▸ Thinking (%d chars, ctrl+t to expand)", len([]rune(reasoning)))) + "\n"
 	}
+	return style.Render("▾ Thinking\n"+reasoning) + "\n\n"
 }
 
-func streamNext(textCh <-chan string, errCh <-chan error) tea.Cmd {
+// streamNext waits for the next event the UI cares about
+func streamNext(stream *zhipu.Stream) tea.Cmd {
 	return func() tea.Msg {
-		if textCh == nil || errCh == nil {
+		if stream == nil {
 			return streamDoneMsg{}
 		}
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				return streamDoneMsg{}
-			}
-			if text == "" {
-				return streamDoneMsg{}
-			}
-			return streamingMsg{text: text}
-		case err, ok := <-errCh:
-			if ok && err != nil {
-				return errorMsg{err: err}
+		for stream.Next() {
+			ev := stream.Event()
+			switch ev.Type {
+			case zhipu.EventTextDelta, zhipu.EventReasoningDelta, zhipu.EventUsage, zhipu.EventWebSearch:
+				return streamingMsg{event: ev}
+			case zhipu.EventError:
+				return errorMsg{err: ev.Err}
 			}
-			return streamDoneMsg{}
 		}
+		return streamDoneMsg{}
+	}
+}
+
+// startResearch streams a rumination session into a new assistant message
+func (m Model) startResearch(question string) (tea.Model, tea.Cmd) {
+	if m.provider == nil {
+		return m, func() tea.Msg {
+			return errorMsg{err: m.providerErr}
+		}
+	}
+	if m.client == nil {
+		return m, func() tea.Msg {
+			return errorMsg{err: fmt.Errorf("/research needs the Z.AI provider")}
+		}
+	}
+	m.addMessage("user", "/research "+question)
+	m.research = &agents.ResearchReport{Question: question}
+	m.loading = true
+	m.statusMessage = "Researching with " + zhipu.ModelGLMZ1Rumination
+	provider := m.provider
+	return m, func() tea.Msg {
+		stream, err := agents.StartResearch(context.Background(), provider, question)
+		if err != nil {
+			return errorMsg{err: err}
+		}
+		return startStreamMsg{stream: stream}
+	}
+}
+
+// finishResearch replaces the streamed answer with the cited report
+func (m *Model) finishResearch() {
+	if m.currentSession != nil && len(m.currentSession.Messages) > 0 {
+		last := &m.currentSession.Messages[len(m.currentSession.Messages)-1]
+		last.Content = m.research.Markdown()
+	}
+	m.statusMessage = fmt.Sprintf("Research report saved in the session (%d sources)", len(m.research.Sources))
+	m.research = nil
+}
+
+// closeStream releases the active stream, if any
+func (m *Model) closeStream() {
+	if m.stream != nil {
+		m.stream.Close()
+		m.stream = nil
 	}
 }
 
@@ -1,24 +1,28 @@
 package zhipu
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
 )
 
 type AgentChatRequest struct {
-	AgentID         string      `json:"agent_id"`
-	Stream          bool        `json:"stream,omitempty"`
-	Messages        []Message   `json:"messages"`
+	AgentID  string    `json:"agent_id"`
+	Stream   bool      `json:"stream,omitempty"`
+	Messages []Message `json:"messages"`
+	// ConversationID continues an earlier conversation; the agent keeps its
+	// history, so only the new messages need to be sent
+	ConversationID  string      `json:"conversation_id,omitempty"`
+	RequestID       string      `json:"request_id,omitempty"`
 	CustomVariables interface{} `json:"custom_variables,omitempty"`
 }
 
 type AgentChatResponse struct {
-	ID      string `json:"id"`
-	AgentID string `json:"agent_id"`
-	Choices []struct {
+	ID             string `json:"id"`
+	AgentID        string `json:"agent_id"`
+	ConversationID string `json:"conversation_id"`
+	Choices        []struct {
 		Index        int    `json:"index"`
 		FinishReason string `json:"finish_reason"`
 		Messages     []struct {
@@ -37,29 +41,133 @@ type AgentChatResponse struct {
 	} `json:"usage"`
 }
 
+// Text returns the text of the agent's reply messages
+func (r *AgentChatResponse) Text() string {
+	var b strings.Builder
+	for _, choice := range r.Choices {
+		for _, msg := range choice.Messages {
+			if msg.Content.Type == "" || msg.Content.Type == "text" {
+				b.WriteString(msg.Content.Text)
+			}
+		}
+	}
+	return b.String()
+}
+
+// NewAgentMessage builds a user message for a hosted agent, attaching files
+// by URL (e.g. the URL of an uploaded file)
+func NewAgentMessage(text string, fileURLs ...string) Message {
+	parts := []ContentPart{{Type: "text", Text: text}}
+	for _, url := range fileURLs {
+		parts = append(parts, ContentPart{Type: "file_url", FileURL: &FileURL{URL: url}})
+	}
+	return Message{Role: "user", Content: parts}
+}
+
 func (c *Client) AgentChat(ctx context.Context, req *AgentChatRequest) (*AgentChatResponse, error) {
-	body, err := json.Marshal(req)
+	r := *req
+	r.Stream = false
+	body, err := json.Marshal(&r)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.z.ai/api/v1/agents", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(ctx, "POST", c.agentsEndpoint(), body, "")
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, withRequestID(err, req.RequestID)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, parseAPIError(resp)
-	}
 	var out AgentChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 	return &out, nil
 }
+
+// AgentChatStream streams a hosted agent's reply. Once the stream has
+// started, its ConversationID continues the conversation.
+func (c *Client) AgentChatStream(ctx context.Context, req *AgentChatRequest) (*Stream, error) {
+	r := *req
+	r.Stream = true
+	body, err := json.Marshal(&r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	resp, err := c.do(ctx, "POST", c.agentsEndpoint(), body, "text/event-stream")
+	if err != nil {
+		return nil, withRequestID(err, req.RequestID)
+	}
+	stream := NewStream(resp.Body, req.RequestID)
+	stream.decode = decodeAgentChunk
+	stream.convID = req.ConversationID
+	return stream, nil
+}
+
+// agentStreamChunk is a streamed agent reply. Its delta content is a string,
+// a part, or a list of parts depending on the agent.
+type agentStreamChunk struct {
+	ID             string `json:"id"`
+	RequestID      string `json:"request_id"`
+	ConversationID string `json:"conversation_id"`
+	Choices        []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+	Error *struct {
+		Code    errorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
+}
+
+// decodeAgentChunk converts an agent chunk to the chat chunk Stream handles
+func decodeAgentChunk(payload []byte) (*streamChunk, error) {
+	var in agentStreamChunk
+	if err := json.Unmarshal(payload, &in); err != nil {
+		return nil, err
+	}
+	out := &streamChunk{
+		ID:             in.ID,
+		RequestID:      in.RequestID,
+		ConversationID: in.ConversationID,
+		Usage:          in.Usage,
+		Error:          in.Error,
+	}
+	out.Choices = make([]streamChoice, len(in.Choices))
+	for i, choice := range in.Choices {
+		out.Choices[i].Index = choice.Index
+		out.Choices[i].Delta.Role = choice.Delta.Role
+		out.Choices[i].Delta.Content = agentContentText(choice.Delta.Content)
+		out.Choices[i].FinishReason = choice.FinishReason
+	}
+	return out, nil
+}
+
+// agentContentText extracts the text of agent content in any of its forms
+func agentContentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []ContentPart
+	if json.Unmarshal(raw, &parts) != nil {
+		var part ContentPart
+		if json.Unmarshal(raw, &part) != nil {
+			return ""
+		}
+		parts = []ContentPart{part}
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Type == "" || part.Type == "text" {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
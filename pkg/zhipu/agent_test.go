@@ -0,0 +1,68 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgentChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true || req["conversation_id"] != "conv-0" {
+			t.Errorf("request = %v", req)
+		}
+		msgs, _ := req["messages"].([]interface{})
+		if len(msgs) != 1 {
+			t.Fatalf("messages = %v", req["messages"])
+		}
+		parts, _ := msgs[0].(map[string]interface{})["content"].([]interface{})
+		if len(parts) != 2 || parts[1].(map[string]interface{})["type"] != "file_url" {
+			t.Errorf("content = %v", parts)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"1","conversation_id":"conv-1","choices":[{"index":0,"delta":{"role":"assistant","content":[{"type":"text","text":"Hel"}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"1","conversation_id":"conv-1","choices":[{"index":0,"delta":{"content":{"type":"text","text":"lo"}}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"1","conversation_id":"conv-1","choices":[{"index":0,"delta":{"content":"!"},"finish_reason":"stop"}],"usage":{"total_tokens":7}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithAgentsURL(server.URL))
+	stream, err := c.AgentChatStream(context.Background(), &AgentChatRequest{
+		AgentID:        "translator",
+		Messages:       []Message{NewAgentMessage("translate this", "https://example.com/doc.pdf")},
+		ConversationID: "conv-0",
+	})
+	if err != nil {
+		t.Fatalf("AgentChatStream failed: %v", err)
+	}
+	result := CollectStream(stream)
+	if result.Error != nil {
+		t.Fatalf("stream error: %v", result.Error)
+	}
+	if result.Content != "Hello!" || result.FinishReason != "stop" {
+		t.Errorf("content = %q, finish = %q", result.Content, result.FinishReason)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 7 {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+	if stream.ConversationID() != "conv-1" {
+		t.Errorf("ConversationID() = %q, want conv-1", stream.ConversationID())
+	}
+}
+
+func TestAgentChatText(t *testing.T) {
+	var resp AgentChatResponse
+	data := `{"conversation_id":"c","choices":[{"messages":[{"role":"assistant","content":{"type":"text","text":"Bonjour"}}]}]}`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "Bonjour" || resp.ConversationID != "c" {
+		t.Errorf("Text() = %q, ConversationID = %q", resp.Text(), resp.ConversationID)
+	}
+}
@@ -0,0 +1,116 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Async task states
+const (
+	TaskProcessing = "PROCESSING"
+	TaskSuccess    = "SUCCESS"
+	TaskFailed     = "FAIL"
+)
+
+// DefaultAsyncPollInterval is how often WaitAsyncResult polls by default
+const DefaultAsyncPollInterval = 5 * time.Second
+
+// ErrTaskFailed is returned by WaitAsyncResult when the job itself failed
+var ErrTaskFailed = errors.New("async task failed")
+
+// AsyncTask identifies a submitted asynchronous completion
+type AsyncTask struct {
+	ID         string `json:"id"`
+	RequestID  string `json:"request_id"`
+	Model      string `json:"model"`
+	TaskStatus string `json:"task_status"`
+}
+
+// AsyncResult is the state of an asynchronous completion. Choices and Usage
+// are set once TaskStatus is TaskSuccess.
+type AsyncResult struct {
+	ChatResponse
+	TaskStatus string `json:"task_status"`
+}
+
+// Done reports whether the task has finished, successfully or not
+func (r *AsyncResult) Done() bool {
+	return r.TaskStatus == TaskSuccess || r.TaskStatus == TaskFailed
+}
+
+// ChatAsync submits a completion to run in the background and returns the
+// task to poll with GetAsyncResult or WaitAsyncResult
+func (c *Client) ChatAsync(ctx context.Context, req *ChatRequest) (*AsyncTask, error) {
+	if err := c.checkContext(req); err != nil {
+		return nil, err
+	}
+	r := *req
+	r.Stream = false
+	body, err := marshalChatRequest(&r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	resp, err := c.do(ctx, "POST", c.baseURL+"/async/chat/completions", body, "")
+	if err != nil {
+		return nil, withRequestID(err, req.RequestID)
+	}
+	defer resp.Body.Close()
+
+	var task AsyncTask
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if task.ID == "" {
+		return nil, fmt.Errorf("async submit returned no task id")
+	}
+	return &task, nil
+}
+
+// GetAsyncResult fetches the current state of an asynchronous completion
+func (c *Client) GetAsyncResult(ctx context.Context, taskID string) (*AsyncResult, error) {
+	resp, err := c.do(ctx, "GET", c.baseURL+"/async-result/"+url.PathEscape(taskID), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result AsyncResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// WaitAsyncResult polls until the task finishes or ctx is done. Transient
+// failures while polling are tolerated, since the job keeps running
+// server-side; only permanent API errors stop the wait early.
+func (c *Client) WaitAsyncResult(ctx context.Context, taskID string, interval time.Duration) (*AsyncResult, error) {
+	if interval <= 0 {
+		interval = DefaultAsyncPollInterval
+	}
+	for {
+		result, err := c.GetAsyncResult(ctx, taskID)
+		switch {
+		case err == nil && result.TaskStatus == TaskFailed:
+			return result, fmt.Errorf("%w: task %s", ErrTaskFailed, taskID)
+		case err == nil && result.Done():
+			return result, nil
+		case err == nil && result.TaskStatus != TaskProcessing:
+			return result, fmt.Errorf("task %s: unknown status %q", taskID, result.TaskStatus)
+		case err != nil:
+			var apiErr *APIError
+			if ctx.Err() != nil || (errors.As(err, &apiErr) && !apiErr.Temporary()) {
+				return nil, err
+			}
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
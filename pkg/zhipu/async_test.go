@@ -0,0 +1,104 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatAsyncAndWait(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/async/chat/completions":
+			w.Write([]byte(`{"id":"task-1","request_id":"req-1","model":"glm-4-32b-0414","task_status":"PROCESSING"}`))
+		case r.Method == "GET" && r.URL.Path == "/async-result/task-1":
+			polls++
+			switch polls {
+			case 1:
+				w.Write([]byte(`{"id":"task-1","task_status":"PROCESSING"}`))
+			case 2:
+				// A flaky poll must not abort the wait
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.Write([]byte(`{"id":"task-1","task_status":"SUCCESS","choices":[{"message":{"role":"assistant","content":"done"}}],"usage":{"total_tokens":9}}`))
+			}
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{}))
+	ctx := context.Background()
+
+	task, err := c.ChatAsync(ctx, &ChatRequest{Model: ModelGLM4_32B, Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("ChatAsync failed: %v", err)
+	}
+	if task.ID != "task-1" || task.TaskStatus != TaskProcessing {
+		t.Errorf("task = %+v", task)
+	}
+
+	result, err := c.WaitAsyncResult(ctx, task.ID, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitAsyncResult failed: %v", err)
+	}
+	if content, _ := result.Choices[0].Message.Content.(string); content != "done" {
+		t.Errorf("content = %q, want %q", content, "done")
+	}
+	if result.Usage.TotalTokens != 9 {
+		t.Errorf("usage = %d, want 9", result.Usage.TotalTokens)
+	}
+	if polls != 3 {
+		t.Errorf("polls = %d, want 3", polls)
+	}
+}
+
+func TestWaitAsyncResultFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"task-2","task_status":"FAIL"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	if _, err := c.WaitAsyncResult(context.Background(), "task-2", time.Millisecond); !errors.Is(err, ErrTaskFailed) {
+		t.Errorf("err = %v, want ErrTaskFailed", err)
+	}
+}
+
+func TestWaitAsyncResultUnknownStatus(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		w.Write([]byte(`{"id":"task-4","task_status":"CANCELLED"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	_, err := c.WaitAsyncResult(context.Background(), "task-4", time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "CANCELLED") {
+		t.Errorf("err = %v, want an unknown status error", err)
+	}
+	if polls != 1 {
+		t.Errorf("polls = %d, want 1", polls)
+	}
+}
+
+func TestWaitAsyncResultContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"task-3","task_status":"PROCESSING"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitAsyncResult(ctx, "task-3", 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,297 @@
+package zhipu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// BatchEndpointChat is the endpoint batch lines are sent to
+const BatchEndpointChat = "/v4/chat/completions"
+
+// Batch states
+const (
+	BatchValidating = "validating"
+	BatchFailed     = "failed"
+	BatchInProgress = "in_progress"
+	BatchFinalizing = "finalizing"
+	BatchCompleted  = "completed"
+	BatchExpired    = "expired"
+	BatchCancelling = "cancelling"
+	BatchCancelled  = "cancelled"
+)
+
+// DefaultBatchPollInterval is how often WaitBatch polls by default
+const DefaultBatchPollInterval = 30 * time.Second
+
+// Batch is a bulk job running the lines of an uploaded JSONL file
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileID     string            `json:"output_file_id"`
+	ErrorFileID      string            `json:"error_file_id"`
+	CreatedAt        int64             `json:"created_at"`
+	CompletedAt      int64             `json:"completed_at"`
+	Metadata         map[string]string `json:"metadata"`
+	RequestCounts    struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// Done reports whether the batch has reached a final state
+func (b *Batch) Done() bool {
+	switch b.Status {
+	case BatchCompleted, BatchFailed, BatchExpired, BatchCancelled:
+		return true
+	}
+	return false
+}
+
+// CreateBatchRequest starts a batch over an uploaded input file
+type CreateBatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// BatchList is one page of batches
+type BatchList struct {
+	Data    []Batch `json:"data"`
+	HasMore bool    `json:"has_more"`
+}
+
+// CreateBatch starts a batch. Endpoint and CompletionWindow default to chat
+// completions and 24h.
+func (c *Client) CreateBatch(ctx context.Context, req *CreateBatchRequest) (*Batch, error) {
+	r := *req
+	if r.Endpoint == "" {
+		r.Endpoint = BatchEndpointChat
+	}
+	if r.CompletionWindow == "" {
+		r.CompletionWindow = "24h"
+	}
+	var batch Batch
+	if err := c.batchCall(ctx, "POST", "/batches", &r, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetBatch fetches the current state of a batch
+func (c *Client) GetBatch(ctx context.Context, batchID string) (*Batch, error) {
+	var batch Batch
+	if err := c.batchCall(ctx, "GET", "/batches/"+url.PathEscape(batchID), nil, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// CancelBatch asks for a running batch to stop
+func (c *Client) CancelBatch(ctx context.Context, batchID string) (*Batch, error) {
+	var batch Batch
+	if err := c.batchCall(ctx, "POST", "/batches/"+url.PathEscape(batchID)+"/cancel", nil, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches lists batches, newest first. after is the last ID of the
+// previous page, or empty for the first page.
+func (c *Client) ListBatches(ctx context.Context, after string, limit int) (*BatchList, error) {
+	q := url.Values{}
+	if after != "" {
+		q.Set("after", after)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/batches"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var list BatchList
+	if err := c.batchCall(ctx, "GET", path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// WaitBatch polls until the batch reaches a final state or ctx is done
+func (c *Client) WaitBatch(ctx context.Context, batchID string, interval time.Duration) (*Batch, error) {
+	if interval <= 0 {
+		interval = DefaultBatchPollInterval
+	}
+	for {
+		batch, err := c.GetBatch(ctx, batchID)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Done() {
+			return batch, nil
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) batchCall(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+	}
+	resp, err := c.do(ctx, method, c.baseURL+path, body, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// UploadBatchInput uploads the JSONL of input and returns the file ID to
+// start a batch with
+func (c *Client) UploadBatchInput(ctx context.Context, input *BatchInput) (string, error) {
+	file, err := c.UploadFile(ctx, &UploadFileRequest{
+		Filename: "batch.jsonl",
+		Purpose:  FilePurposeBatch,
+		Content:  input.Reader(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return file.ID, nil
+}
+
+// BatchOutput downloads a result file of a finished batch, its
+// OutputFileID or ErrorFileID. The caller must close the returned reader.
+func (c *Client) BatchOutput(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return c.FileContent(ctx, fileID)
+}
+
+// BatchInput builds the JSONL input file of a batch
+type BatchInput struct {
+	buf bytes.Buffer
+	n   int
+}
+
+type batchInputLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// Add appends a chat request. customID must be unique within the batch and
+// is how results are matched back to inputs.
+func (b *BatchInput) Add(customID string, req *ChatRequest) error {
+	body, err := marshalChatRequest(req)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(batchInputLine{
+		CustomID: customID,
+		Method:   "POST",
+		URL:      BatchEndpointChat,
+		Body:     body,
+	})
+	if err != nil {
+		return err
+	}
+	b.buf.Write(line)
+	b.buf.WriteByte('\n')
+	b.n++
+	return nil
+}
+
+// Len returns the number of requests added
+func (b *BatchInput) Len() int {
+	return b.n
+}
+
+// Reader returns the JSONL content, ready for UploadFile
+func (b *BatchInput) Reader() io.Reader {
+	return bytes.NewReader(b.buf.Bytes())
+}
+
+// BatchResult is the outcome of one batch line
+type BatchResult struct {
+	CustomID string
+	Response *ChatResponse // nil when Err is set
+	Err      error
+}
+
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    errorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
+}
+
+// ParseBatchResults reads a batch output or error file. Failed lines are
+// returned with Err set to an *APIError.
+func ParseBatchResults(r io.Reader) ([]BatchResult, error) {
+	var results []BatchResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var out batchOutputLine
+		if err := json.Unmarshal(line, &out); err != nil {
+			return results, fmt.Errorf("parse batch line %d: %w", len(results)+1, err)
+		}
+		results = append(results, out.result())
+	}
+	return results, scanner.Err()
+}
+
+func (l *batchOutputLine) result() BatchResult {
+	res := BatchResult{CustomID: l.CustomID}
+	switch {
+	case l.Error != nil && l.Error.Message != "":
+		res.Err = &APIError{Code: string(l.Error.Code), Message: l.Error.Message}
+	case l.Response == nil:
+		res.Err = fmt.Errorf("batch line %s has no response", l.CustomID)
+	case l.Response.StatusCode < 200 || l.Response.StatusCode >= 300:
+		apiErr := ParseAPIError(l.Response.StatusCode, nil, l.Response.Body)
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = l.Response.RequestID
+		}
+		res.Err = apiErr
+	default:
+		var resp ChatResponse
+		if err := json.Unmarshal(l.Response.Body, &resp); err != nil {
+			res.Err = fmt.Errorf("decode batch response %s: %w", l.CustomID, err)
+		} else {
+			res.Response = &resp
+		}
+	}
+	return res
+}
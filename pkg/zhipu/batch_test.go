@@ -0,0 +1,141 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchInput(t *testing.T) {
+	var input BatchInput
+	for _, id := range []string{"golem-a.go", "golem-b.go"} {
+		err := input.Add(id, &ChatRequest{
+			Model:    ModelGLM4_32B,
+			Messages: []Message{{Role: "assistant", Content: "x", ReasoningContent: "secret"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if input.Len() != 2 {
+		t.Errorf("Len = %d, want 2", input.Len())
+	}
+
+	data, _ := io.ReadAll(input.Reader())
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(lines))
+	}
+	var line struct {
+		CustomID string      `json:"custom_id"`
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Body     ChatRequest `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.CustomID != "golem-b.go" || line.Method != "POST" || line.URL != BatchEndpointChat {
+		t.Errorf("line = %+v", line)
+	}
+	if strings.Contains(lines[1], "secret") {
+		t.Error("reasoning_content must not be sent in batch bodies")
+	}
+}
+
+func TestParseBatchResults(t *testing.T) {
+	output := `{"custom_id":"ok","response":{"status_code":200,"body":{"id":"c1","choices":[{"message":{"role":"assistant","content":"fine"}}]}}}
+{"custom_id":"bad","response":{"status_code":400,"request_id":"r2","body":{"error":{"code":"1301","message":"blocked"}}}}
+
+{"custom_id":"gone","error":{"code":"1210","message":"invalid line"}}
+`
+	results, err := ParseBatchResults(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %d, want 3", len(results))
+	}
+	if content, _ := results[0].Response.Choices[0].Message.Content.(string); content != "fine" {
+		t.Errorf("content = %q, want %q", content, "fine")
+	}
+	var apiErr *APIError
+	if !errors.As(results[1].Err, &apiErr) || apiErr.RequestID != "r2" || !errors.Is(results[1].Err, ErrContentFiltered) {
+		t.Errorf("results[1].Err = %v", results[1].Err)
+	}
+	if results[2].Err == nil || results[2].Response != nil {
+		t.Errorf("results[2] = %+v, want error", results[2])
+	}
+}
+
+func TestBatchWorkflow(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/files":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Fatalf("parse multipart: %v", err)
+			}
+			if r.FormValue("purpose") != FilePurposeBatch {
+				t.Errorf("purpose = %q", r.FormValue("purpose"))
+			}
+			w.Write([]byte(`{"id":"file-in","purpose":"batch"}`))
+		case r.Method == "POST" && r.URL.Path == "/batches":
+			var req CreateBatchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.InputFileID != "file-in" || req.Endpoint != BatchEndpointChat || req.CompletionWindow != "24h" {
+				t.Errorf("create = %+v", req)
+			}
+			w.Write([]byte(`{"id":"batch-1","status":"validating"}`))
+		case r.Method == "GET" && r.URL.Path == "/batches/batch-1":
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"id":"batch-1","status":"in_progress"}`))
+				return
+			}
+			w.Write([]byte(`{"id":"batch-1","status":"completed","output_file_id":"file-out","request_counts":{"total":1,"completed":1}}`))
+		case r.Method == "GET" && r.URL.Path == "/files/file-out/content":
+			w.Write([]byte(`{"custom_id":"golem-a.go","response":{"status_code":200,"body":{"choices":[{"message":{"content":"ok"}}]}}}` + "\n"))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	ctx := context.Background()
+
+	var input BatchInput
+	input.Add("golem-a.go", &ChatRequest{Model: ModelGLM4_32B})
+	fileID, err := c.UploadBatchInput(ctx, &input)
+	if err != nil {
+		t.Fatalf("UploadBatchInput failed: %v", err)
+	}
+	batch, err := c.CreateBatch(ctx, &CreateBatchRequest{InputFileID: fileID})
+	if err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	batch, err = c.WaitBatch(ctx, batch.ID, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitBatch failed: %v", err)
+	}
+	if batch.Status != BatchCompleted || batch.RequestCounts.Completed != 1 {
+		t.Errorf("batch = %+v", batch)
+	}
+
+	content, err := c.BatchOutput(ctx, batch.OutputFileID)
+	if err != nil {
+		t.Fatalf("BatchOutput failed: %v", err)
+	}
+	defer content.Close()
+	results, err := ParseBatchResults(content)
+	if err != nil || len(results) != 1 || results[0].CustomID != "golem-a.go" {
+		t.Errorf("results = %+v, err = %v", results, err)
+	}
+}
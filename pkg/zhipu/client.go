@@ -2,14 +2,10 @@
 package zhipu
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -29,24 +25,43 @@ const (
 
 	// Embedding
 	ModelEmbedding3 = "embedding-3"
+
+	// Image generation
+	ModelCogView4      = "cogview-4-250304"
+	ModelCogView3Flash = "cogview-3-flash"
 )
 
 // Client is the Zhipu AI API client
 type Client struct {
-	apiKey     string
-	httpClient *http.Client
-	baseURL    string
+	apiKey            string
+	httpClient        *http.Client
+	baseURL           string
+	agentsURL         string
+	knowledgeURL      string
+	contextGuard      bool
+	headers           http.Header
+	timeout           time.Duration
+	streamIdleTimeout time.Duration
+	retry             RetryPolicy
+	embeddingCache    *EmbeddingCache
 }
 
 // NewClient creates a new Zhipu AI client
-func NewClient(apiKey string) *Client {
-	return &Client{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
-		baseURL: BaseURL,
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
+		apiKey:            apiKey,
+		httpClient:        &http.Client{},
+		baseURL:           BaseURL,
+		headers:           make(http.Header),
+		timeout:           DefaultTimeout,
+		contextGuard:      true,
+		streamIdleTimeout: DefaultStreamIdleTimeout,
+		retry:             DefaultRetryPolicy(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Message represents a chat message
@@ -55,13 +70,18 @@ type Message struct {
 	Content    interface{} `json:"content"` // string or []ContentPart
 	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
 	ToolCallID string      `json:"tool_call_id,omitempty"`
+
+	// ReasoningContent holds the thinking trace of Z1 / thinking models.
+	// It is kept for display and storage but never sent back to the model.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 // ContentPart for multimodal content
 type ContentPart struct {
-	Type     string    `json:"type"` // "text" or "image_url"
+	Type     string    `json:"type"` // "text", "image_url" or "file_url"
 	Text     string    `json:"text,omitempty"`
 	ImageURL *ImageURL `json:"image_url,omitempty"`
+	FileURL  *FileURL  `json:"file_url,omitempty"`
 }
 
 // ImageURL for vision models
@@ -69,6 +89,11 @@ type ImageURL struct {
 	URL string `json:"url"`
 }
 
+// FileURL attaches a file to a hosted agent message
+type FileURL struct {
+	URL string `json:"url"`
+}
+
 // Tool definition for function calling
 type Tool struct {
 	Type      string     `json:"type"` // "function" or "web_search" or "retrieval" or "code_interpreter"
@@ -86,8 +111,9 @@ type Function struct {
 
 // WebSearch tool config
 type WebSearch struct {
-	Enable      bool   `json:"enable"`
-	SearchQuery string `json:"search_query,omitempty"`
+	Enable       bool   `json:"enable"`
+	SearchQuery  string `json:"search_query,omitempty"`
+	SearchResult bool   `json:"search_result,omitempty"` // Return the consulted pages
 }
 
 // Retrieval tool config (RAG)
@@ -106,73 +132,110 @@ type ToolCall struct {
 	} `json:"function"`
 }
 
-// ChatRequest for chat completions
+// ChatRequest for chat completions. Optional sampling parameters are
+// pointers so that an explicit zero is sent rather than dropped; use the
+// Float64, Int and Bool helpers to set them.
 type ChatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature,omitempty"`
-	TopP        float64   `json:"top_p,omitempty"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Stream      bool      `json:"stream,omitempty"`
-	Tools       []Tool    `json:"tools,omitempty"`
-	ToolChoice  string    `json:"tool_choice,omitempty"` // "auto" or "none"
-
-	// Z1 specific
-	DoSample bool `json:"do_sample,omitempty"`
+	Model       string      `json:"model"`
+	Messages    []Message   `json:"messages"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	TopP        *float64    `json:"top_p,omitempty"`
+	MaxTokens   int         `json:"max_tokens,omitempty"`
+	Stop        []string    `json:"stop,omitempty"`
+	N           *int        `json:"n,omitempty"`
+	Seed        *int        `json:"seed,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+
+	// ParallelToolCalls set to false limits the model to one call per turn
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// ResponseFormat switches on JSON mode; see ChatJSON
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// RequestID is echoed in responses and errors; the API generates one
+	// when empty. UserID identifies the end user for abuse monitoring.
+	RequestID string `json:"request_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+
+	// DoSample false makes decoding greedy, ignoring temperature and top_p
+	DoSample *bool `json:"do_sample,omitempty"`
+
+	// Thinking toggles the reasoning phase of hybrid thinking models
+	Thinking *Thinking `json:"thinking,omitempty"`
 
 	// Rumination specific
 	SearchMode string `json:"search_mode,omitempty"` // "search_std" or "search_pro"
 }
 
+// Thinking types
+const (
+	ThinkingEnabled  = "enabled"
+	ThinkingDisabled = "disabled"
+)
+
+// Thinking controls deep thinking on models that support it
+type Thinking struct {
+	Type string `json:"type"`
+}
+
+// Float64 returns a pointer to v, for optional request fields
+func Float64(v float64) *float64 { return &v }
+
+// Int returns a pointer to v, for optional request fields
+func Int(v int) *int { return &v }
+
+// Bool returns a pointer to v, for optional request fields
+func Bool(v bool) *bool { return &v }
+
 // ChatResponse from chat completions
 type ChatResponse struct {
-	ID      string `json:"id"`
-	Created int64  `json:"created"`
-	Model   string `json:"model"`
-	Choices []struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	Created   int64  `json:"created"`
+	Model     string `json:"model"`
+	Choices   []struct {
 		Index        int     `json:"index"`
 		Message      Message `json:"message"`
 		FinishReason string  `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage Usage `json:"usage"`
 
 	// Rumination specific
-	WebSearch []struct {
-		Title   string `json:"title"`
-		Link    string `json:"link"`
-		Content string `json:"content"`
-	} `json:"web_search,omitempty"`
+	WebSearch []WebSearchResult `json:"web_search,omitempty"`
+}
+
+// Usage reports token consumption of a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add accumulates the counts of another request
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
 }
 
 // Chat sends a chat completion request
 func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+	if err := c.checkContext(req); err != nil {
+		return nil, err
 	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
+	body, err := marshalChatRequest(req)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(ctx, "POST", c.baseURL+"/chat/completions", body, "")
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, withRequestID(err, req.RequestID)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, parseAPIError(resp)
-	}
-
 	var chatResp ChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
@@ -181,140 +244,34 @@ func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, err
 	return &chatResp, nil
 }
 
-// ChatStream sends a streaming chat completion request
-func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (<-chan string, <-chan error) {
-	req.Stream = true
-
-	textCh := make(chan string)
-	errCh := make(chan error, 1)
-
-	go func() {
-		defer close(textCh)
-		defer close(errCh)
-
-		body, err := json.Marshal(req)
-		if err != nil {
-			errCh <- fmt.Errorf("marshal request: %w", err)
-			return
-		}
-
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
-		if err != nil {
-			errCh <- fmt.Errorf("create request: %w", err)
-			return
-		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-		httpReq.Header.Set("Accept", "text/event-stream")
-
-		resp, err := c.httpClient.Do(httpReq)
-		if err != nil {
-			errCh <- fmt.Errorf("do request: %w", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			errCh <- parseAPIError(resp)
-			return
-		}
-
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" || !strings.HasPrefix(line, "data:") {
-				continue
-			}
-			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-			if payload == "[DONE]" {
-				return
-			}
-			var event map[string]interface{}
-			if err := json.Unmarshal([]byte(payload), &event); err != nil {
-				continue
-			}
-			if choices, ok := event["choices"].([]interface{}); ok && len(choices) > 0 {
-				choice, _ := choices[0].(map[string]interface{})
-				if delta, ok := choice["delta"].(map[string]interface{}); ok {
-					if content, ok := delta["content"].(string); ok && content != "" {
-						textCh <- content
-					}
-				}
-				if message, ok := choice["message"].(map[string]interface{}); ok {
-					if content, ok := message["content"].(string); ok && content != "" {
-						textCh <- content
-					}
-				}
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			errCh <- err
-		}
-	}()
-
-	return textCh, errCh
+// marshalChatRequest encodes a request without the reasoning traces stored
+// on previous assistant messages
+func marshalChatRequest(req *ChatRequest) ([]byte, error) {
+	stripped := *req
+	stripped.Messages = make([]Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.ReasoningContent = ""
+		stripped.Messages[i] = msg
+	}
+	return json.Marshal(&stripped)
 }
 
-// Embedding generates embeddings
+// Embedding generates the embedding of a single text; see Embeddings for
+// batches, dimensions and usage
 func (c *Client) Embedding(ctx context.Context, input string, model string) ([]float64, error) {
-	if model == "" {
-		model = ModelEmbedding3
-	}
-
-	reqBody := map[string]interface{}{
-		"model": model,
-		"input": input,
-	}
-
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.Embeddings(ctx, &EmbeddingRequest{Model: model, Input: []string{input}})
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var embResp struct {
-		Data []struct {
-			Embedding []float64 `json:"embedding"`
-		} `json:"data"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
-	}
-
-	if len(embResp.Data) == 0 {
+	if len(resp.Embeddings) == 0 || resp.Embeddings[0] == nil {
 		return nil, fmt.Errorf("no embedding data")
 	}
 
-	return embResp.Data[0].Embedding, nil
-}
-
-func parseAPIError(resp *http.Response) error {
-	body, _ := io.ReadAll(resp.Body)
-	var payload struct {
-		Error struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
+	vec := make([]float64, len(resp.Embeddings[0]))
+	for i, v := range resp.Embeddings[0] {
+		vec[i] = float64(v)
 	}
-	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
-		return fmt.Errorf("API error %d/%d: %s", resp.StatusCode, payload.Error.Code, payload.Error.Message)
-	}
-	return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	return vec, nil
 }
 
 // CodeGeeX specific methods
@@ -353,7 +310,7 @@ func NewFunctionTool(name, description string, parameters interface{}) Tool {
 
 // JSONSchema helpers for building function parameters
 type JSONSchema struct {
-	Type        string                 `json:"type"`
+	Type        string                 `json:"type,omitempty"` // empty accepts any value
 	Description string                 `json:"description,omitempty"`
 	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
 	Required    []string               `json:"required,omitempty"`
@@ -380,6 +337,11 @@ func IntProp(description string) *JSONSchema {
 	return &JSONSchema{Type: "integer", Description: description}
 }
 
+// NumberProp creates a number property schema
+func NumberProp(description string) *JSONSchema {
+	return &JSONSchema{Type: "number", Description: description}
+}
+
 // BoolProp creates a boolean property schema
 func BoolProp(description string) *JSONSchema {
 	return &JSONSchema{Type: "boolean", Description: description}
@@ -1,153 +1,66 @@
 // Package zhipu provides native Z.AI / Zhipu AI API client
-// This file adds enhanced streaming support with tool call handling
+// This file adds streaming support with tool call handling
 package zhipu
 
 import (
-	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 )
 
-// ChatStreamWithTools streams chat completion with tool call support
-// Returns separate channels for text chunks, tool calls, and errors
-func (c *Client) ChatStreamWithTools(ctx context.Context, req *ChatRequest) (<-chan string, <-chan ToolCall, <-chan error) {
+// ChatStream sends a streaming chat completion request. Request failures
+// (after retries) are returned directly; failures once the stream has
+// started are reported as an EventError and by Stream.Err.
+func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (*Stream, error) {
 	req.Stream = true
+	if err := c.checkContext(req); err != nil {
+		return nil, err
+	}
 
-	textCh := make(chan string, 100)
-	toolCh := make(chan ToolCall, 10)
-	errCh := make(chan error, 1)
-
-	go func() {
-		defer close(textCh)
-		defer close(toolCh)
-		defer close(errCh)
-
-		body, err := json.Marshal(req)
-		if err != nil {
-			errCh <- fmt.Errorf("marshal request: %w", err)
-			return
-		}
-
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
-		if err != nil {
-			errCh <- fmt.Errorf("create request: %w", err)
-			return
-		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-		httpReq.Header.Set("Accept", "text/event-stream")
-
-		resp, err := c.httpClient.Do(httpReq)
-		if err != nil {
-			errCh <- fmt.Errorf("do request: %w", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			errCh <- parseAPIError(resp)
-			return
-		}
-
-		// Accumulate tool call fragments (streamed incrementally)
-		toolCallBuilders := make(map[int]*toolCallBuilder)
-
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			select {
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			default:
-			}
-
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" || !strings.HasPrefix(line, "data:") {
-				continue
-			}
-			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
-			if payload == "[DONE]" {
-				// Emit any completed tool calls
-				for _, b := range toolCallBuilders {
-					if b.complete() {
-						toolCh <- b.build()
-					}
-				}
-				return
-			}
-
-			var event streamEvent
-			if err := json.Unmarshal([]byte(payload), &event); err != nil {
-				continue
-			}
-
-			for _, choice := range event.Choices {
-				// Handle text content
-				if choice.Delta.Content != "" {
-					textCh <- choice.Delta.Content
-				}
-
-				// Handle tool calls (streamed as deltas)
-				for _, tcDelta := range choice.Delta.ToolCalls {
-					idx := tcDelta.Index
-					if _, exists := toolCallBuilders[idx]; !exists {
-						toolCallBuilders[idx] = &toolCallBuilder{}
-					}
-					b := toolCallBuilders[idx]
-
-					if tcDelta.ID != "" {
-						b.id = tcDelta.ID
-					}
-					if tcDelta.Type != "" {
-						b.typ = tcDelta.Type
-					}
-					if tcDelta.Function.Name != "" {
-						b.funcName = tcDelta.Function.Name
-					}
-					if tcDelta.Function.Arguments != "" {
-						b.funcArgs += tcDelta.Function.Arguments
-					}
-				}
-
-				// Check if finish_reason indicates completion
-				if choice.FinishReason == "tool_calls" || choice.FinishReason == "stop" {
-					for _, b := range toolCallBuilders {
-						if b.complete() {
-							toolCh <- b.build()
-						}
-					}
-					toolCallBuilders = make(map[int]*toolCallBuilder)
-				}
-			}
-		}
+	body, err := marshalChatRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
 
-		if err := scanner.Err(); err != nil {
-			errCh <- err
-		}
-	}()
+	// Retries happen inside do, before the first token is read
+	resp, err := c.do(ctx, "POST", c.baseURL+"/chat/completions", body, "text/event-stream")
+	if err != nil {
+		return nil, withRequestID(err, req.RequestID)
+	}
+	return NewStream(resp.Body, req.RequestID), nil
+}
 
-	return textCh, toolCh, errCh
+// streamChunk is one SSE data payload
+type streamChunk struct {
+	ID             string            `json:"id"`
+	RequestID      string            `json:"request_id"`
+	ConversationID string            `json:"conversation_id"` // Hosted agents only
+	Created        int64             `json:"created"`
+	Model          string            `json:"model"`
+	Choices        []streamChoice    `json:"choices"`
+	Usage          *Usage            `json:"usage"`
+	WebSearch      []WebSearchResult `json:"web_search"`
+	Error          *struct {
+		Code    errorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
 }
 
-// streamEvent represents a streaming SSE event
-type streamEvent struct {
-	ID      string `json:"id"`
-	Created int64  `json:"created"`
-	Model   string `json:"model"`
-	Choices []struct {
-		Index int `json:"index"`
-		Delta struct {
-			Role      string          `json:"role,omitempty"`
-			Content   string          `json:"content"`
-			ToolCalls []toolCallDelta `json:"tool_calls"`
-		} `json:"delta"`
-		FinishReason string `json:"finish_reason"`
-	} `json:"choices"`
+// streamChoice is one choice of a streamed chunk
+type streamChoice struct {
+	Index int `json:"index"`
+	Delta struct {
+		Role             string          `json:"role,omitempty"`
+		Content          string          `json:"content"`
+		ReasoningContent string          `json:"reasoning_content"`
+		ToolCalls        []toolCallDelta `json:"tool_calls"`
+	} `json:"delta"`
+	// Some endpoints send a full message instead of a delta
+	Message struct {
+		Content          string `json:"content"`
+		ReasoningContent string `json:"reasoning_content"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
 }
 
 // toolCallDelta represents incremental tool call data in stream
@@ -169,6 +82,29 @@ type toolCallBuilder struct {
 	funcArgs string
 }
 
+func (b *toolCallBuilder) add(d toolCallDelta) {
+	if d.ID != "" {
+		b.id = d.ID
+	}
+	if d.Type != "" {
+		b.typ = d.Type
+	}
+	if d.Function.Name != "" {
+		b.funcName = d.Function.Name
+	}
+	b.funcArgs += d.Function.Arguments
+}
+
+// addCall adds a fragment that arrived as an EventToolCallDelta
+func (b *toolCallBuilder) addCall(call *ToolCall) {
+	var d toolCallDelta
+	d.ID = call.ID
+	d.Type = call.Type
+	d.Function.Name = call.Function.Name
+	d.Function.Arguments = call.Function.Arguments
+	b.add(d)
+}
+
 func (b *toolCallBuilder) complete() bool {
 	return b.id != "" && b.funcName != ""
 }
@@ -189,42 +125,50 @@ func (b *toolCallBuilder) build() ToolCall {
 
 // StreamResult collects all streaming output into a final result
 type StreamResult struct {
-	Content   string
-	ToolCalls []ToolCall
-	Error     error
+	Content      string
+	Reasoning    string
+	ToolCalls    []ToolCall
+	FinishReason string
+	WebSearch    []WebSearchResult
+	Usage        *Usage // nil if the API reported none
+	Error        error
 }
 
-// CollectStream collects all streaming output synchronously
-func CollectStream(textCh <-chan string, toolCh <-chan ToolCall, errCh <-chan error) StreamResult {
-	var result StreamResult
-	var contentBuilder strings.Builder
-
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				textCh = nil
-			} else {
-				contentBuilder.WriteString(text)
-			}
-		case tc, ok := <-toolCh:
-			if !ok {
-				toolCh = nil
-			} else {
-				result.ToolCalls = append(result.ToolCalls, tc)
-			}
-		case err, ok := <-errCh:
-			if ok && err != nil {
-				result.Error = err
-			}
-			errCh = nil
-		}
+// Message returns the assistant message described by the result
+func (r StreamResult) Message() Message {
+	return Message{
+		Role:             "assistant",
+		Content:          r.Content,
+		ToolCalls:        r.ToolCalls,
+		ReasoningContent: r.Reasoning,
+	}
+}
+
+// CollectStream drains a stream synchronously and closes it
+func CollectStream(s *Stream) StreamResult {
+	defer s.Close()
 
-		if textCh == nil && toolCh == nil && errCh == nil {
-			break
+	var result StreamResult
+	var content, reasoning strings.Builder
+	for s.Next() {
+		ev := s.Event()
+		switch ev.Type {
+		case EventTextDelta:
+			content.WriteString(ev.Text)
+		case EventReasoningDelta:
+			reasoning.WriteString(ev.Text)
+		case EventToolCall:
+			result.ToolCalls = append(result.ToolCalls, *ev.ToolCall)
+		case EventFinish:
+			result.FinishReason = ev.FinishReason
+		case EventWebSearch:
+			result.WebSearch = append(result.WebSearch, ev.WebSearch...)
 		}
 	}
 
-	result.Content = contentBuilder.String()
+	result.Content = content.String()
+	result.Reasoning = reasoning.String()
+	result.Usage = s.Usage()
+	result.Error = s.Err()
 	return result
 }
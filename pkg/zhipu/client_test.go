@@ -3,6 +3,9 @@ package zhipu
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -131,7 +134,7 @@ func TestChatStream(t *testing.T) {
 		events := []string{
 			`data: {"id":"1","choices":[{"delta":{"content":"Hello"}}]}`,
 			`data: {"id":"1","choices":[{"delta":{"content":", "}}]}`,
-			`data: {"id":"1","choices":[{"delta":{"content":"world!"}}]}`,
+			`data: {"id":"1","choices":[{"delta":{"content":"world!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`,
 			`data: [DONE]`,
 		}
 
@@ -147,31 +150,33 @@ func TestChatStream(t *testing.T) {
 	c := NewClient("test-key")
 	c.baseURL = server.URL
 
-	textCh, errCh := c.ChatStream(context.Background(), &ChatRequest{
+	stream, err := c.ChatStream(context.Background(), &ChatRequest{
 		Model: ModelGLM4_32B,
 		Messages: []Message{
 			{Role: "user", Content: "Hello!"},
 		},
 	})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	defer stream.Close()
 
 	var content strings.Builder
-	for {
-		select {
-		case text, ok := <-textCh:
-			if !ok {
-				goto done
-			}
-			content.WriteString(text)
-		case err, ok := <-errCh:
-			if ok && err != nil {
-				t.Fatalf("stream error: %v", err)
-			}
+	for stream.Next() {
+		if ev := stream.Event(); ev.Type == EventTextDelta {
+			content.WriteString(ev.Text)
 		}
 	}
-done:
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
 	if content.String() != "Hello, world!" {
 		t.Errorf("streamed content = %q, want %q", content.String(), "Hello, world!")
 	}
+	want := Usage{PromptTokens: 5, CompletionTokens: 3, TotalTokens: 8}
+	if u := stream.Usage(); u == nil || *u != want {
+		t.Errorf("usage = %+v, want %+v", u, want)
+	}
 }
 
 func TestToolDefinition(t *testing.T) {
@@ -212,45 +217,151 @@ func TestToolDefinition(t *testing.T) {
 }
 
 func TestStreamResult(t *testing.T) {
-	textCh := make(chan string, 3)
-	toolCh := make(chan ToolCall, 1)
-	errCh := make(chan error, 1)
-
-	textCh <- "Hello"
-	textCh <- ", "
-	textCh <- "world!"
-	close(textCh)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`data: {"choices":[{"delta":{"content":"Hello, "}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"second","arguments":"{}"}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"test_func","arguments":"{\"arg\": "}}]}}]}`,
+			`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"value\"}"}}]}}]}`,
+			`data: {"choices":[{"delta":{"content":"world!"},"finish_reason":"tool_calls"}]}`,
+			`data: [DONE]`,
+		}
+		for _, event := range events {
+			w.Write([]byte(event + "\n\n"))
+		}
+	}))
+	defer server.Close()
 
-	toolCh <- ToolCall{
-		ID:   "call_123",
-		Type: "function",
-		Function: struct {
-			Name      string `json:"name"`
-			Arguments string `json:"arguments"`
-		}{
-			Name:      "test_func",
-			Arguments: `{"arg": "value"}`,
-		},
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	stream, err := c.ChatStream(context.Background(), &ChatRequest{Model: ModelGLM4_32B})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
 	}
-	close(toolCh)
-	close(errCh)
-
-	result := CollectStream(textCh, toolCh, errCh)
+	result := CollectStream(stream)
 
 	if result.Content != "Hello, world!" {
 		t.Errorf("content = %q, want %q", result.Content, "Hello, world!")
 	}
-	if len(result.ToolCalls) != 1 {
-		t.Fatalf("tool calls = %d, want 1", len(result.ToolCalls))
+	if len(result.ToolCalls) != 2 {
+		t.Fatalf("tool calls = %d, want 2", len(result.ToolCalls))
 	}
 	if result.ToolCalls[0].Function.Name != "test_func" {
 		t.Errorf("tool call name = %q, want %q", result.ToolCalls[0].Function.Name, "test_func")
 	}
+	if result.ToolCalls[0].Function.Arguments != `{"arg": "value"}` {
+		t.Errorf("tool call arguments = %q", result.ToolCalls[0].Function.Arguments)
+	}
+	if result.FinishReason != "tool_calls" {
+		t.Errorf("finish reason = %q, want %q", result.FinishReason, "tool_calls")
+	}
 	if result.Error != nil {
 		t.Errorf("unexpected error: %v", result.Error)
 	}
 }
 
+func TestStreamMidStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"partial"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"request_id":"req-9","error":{"code":"1301","message":"unsafe content"}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	stream, err := c.ChatStream(context.Background(), &ChatRequest{Model: ModelGLM4_32B})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var types []StreamEventType
+	for stream.Next() {
+		types = append(types, stream.Event().Type)
+	}
+	if len(types) != 2 || types[0] != EventTextDelta || types[1] != EventError {
+		t.Errorf("event types = %v, want [text error]", types)
+	}
+	if !errors.Is(stream.Err(), ErrContentFiltered) {
+		t.Errorf("err = %v, want ErrContentFiltered", stream.Err())
+	}
+}
+
+func TestNewSSEStream(t *testing.T) {
+	body := "id:1\nevent:result\n" +
+		"data:{\"text\":\"hi\",\"call\":{\"id\":\"c1\",\"name\":\"f\",\"args\":\"{\\\"a\\\":\"}}\n\n" +
+		"data:{\"call\":{\"args\":\"1}\"},\"finish\":\"tool_calls\"}\n\n" +
+		"data:[DONE]\n\n"
+	decode := func(payload []byte) ([]StreamEvent, error) {
+		var chunk struct {
+			Text   string
+			Finish string
+			Call   *struct{ ID, Name, Args string }
+		}
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return nil, err
+		}
+		var events []StreamEvent
+		if chunk.Text != "" {
+			events = append(events, StreamEvent{Type: EventTextDelta, Text: chunk.Text})
+		}
+		if chunk.Call != nil {
+			call := ToolCall{ID: chunk.Call.ID, Type: "function"}
+			call.Function.Name = chunk.Call.Name
+			call.Function.Arguments = chunk.Call.Args
+			events = append(events, StreamEvent{Type: EventToolCallDelta, ToolCall: &call})
+		}
+		if chunk.Finish != "" {
+			events = append(events, StreamEvent{Type: EventFinish, FinishReason: chunk.Finish})
+		}
+		return events, nil
+	}
+
+	var types []StreamEventType
+	var calls []ToolCall
+	stream := NewSSEStream(io.NopCloser(strings.NewReader(body)), "", decode)
+	for stream.Next() {
+		ev := stream.Event()
+		types = append(types, ev.Type)
+		if ev.Type == EventToolCall {
+			calls = append(calls, *ev.ToolCall)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []StreamEventType{EventTextDelta, EventToolCallDelta, EventToolCallDelta, EventToolCall, EventFinish}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("event types = %v, want %v", types, want)
+	}
+	if len(calls) != 1 || calls[0].Function.Arguments != `{"a":1}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+}
+
+func TestChatRequestExplicitZeros(t *testing.T) {
+	data, err := json.Marshal(&ChatRequest{
+		Model:       ModelGLM4_32B,
+		Temperature: Float64(0),
+		Seed:        Int(0),
+		DoSample:    Bool(false),
+		Thinking:    &Thinking{Type: ThinkingDisabled},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"temperature":0`, `"seed":0`, `"do_sample":false`, `"thinking":{"type":"disabled"}`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request %s missing %s", data, want)
+		}
+	}
+	for _, unset := range []string{"top_p", `"n"`, "stop", "request_id"} {
+		if strings.Contains(string(data), unset) {
+			t.Errorf("request %s should omit %s", data, unset)
+		}
+	}
+}
+
 func TestMessage(t *testing.T) {
 	// Test simple text message
 	msg := Message{
@@ -297,3 +408,49 @@ func TestMultimodalContent(t *testing.T) {
 		t.Error("expected image_url in marshaled data")
 	}
 }
+
+func TestChatStreamWithReasoning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.Contains(fmt.Sprint(req["messages"]), "reasoning_content") {
+			t.Error("reasoning_content must not be sent back to the model")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`data: {"choices":[{"delta":{"reasoning_content":"Let me think. "}}]}`,
+			`data: {"choices":[{"delta":{"reasoning_content":"2+2=4."}}]}`,
+			`data: {"choices":[{"delta":{"content":"4"}}]}`,
+			`data: [DONE]`,
+		}
+		for _, event := range events {
+			w.Write([]byte(event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	stream, err := c.ChatStream(context.Background(), &ChatRequest{
+		Model: ModelGLMZ1_32B,
+		Messages: []Message{
+			{Role: "user", Content: "1+1?"},
+			{Role: "assistant", Content: "2", ReasoningContent: "earlier thoughts"},
+			{Role: "user", Content: "2+2?"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	result := CollectStream(stream)
+	if result.Error != nil {
+		t.Fatalf("stream error: %v", result.Error)
+	}
+	if result.Content != "4" {
+		t.Errorf("content = %q, want %q", result.Content, "4")
+	}
+	if result.Reasoning != "Let me think. 2+2=4." {
+		t.Errorf("reasoning = %q, want %q", result.Reasoning, "Let me think. 2+2=4.")
+	}
+}
@@ -0,0 +1,208 @@
+package zhipu
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// MaxEmbeddingBatch is the most inputs the embeddings endpoint accepts in one
+// request; Embeddings splits larger requests
+const MaxEmbeddingBatch = 64
+
+// EmbeddingRequest embeds one or more texts
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+
+	// Dimensions shortens embedding-3 vectors (256, 512, 1024 or 2048)
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+// EmbeddingResponse holds one vector per input, in input order
+type EmbeddingResponse struct {
+	Model      string
+	Embeddings [][]float32
+	Usage      Usage // Tokens billed; cached inputs cost nothing
+}
+
+type embeddingWireResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
+}
+
+// Embeddings embeds all inputs, batching requests to the provider's limit.
+// With WithEmbeddingCache, inputs embedded before are served from disk.
+func (c *Client) Embeddings(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = ModelEmbedding3
+	}
+	result := &EmbeddingResponse{
+		Model:      model,
+		Embeddings: make([][]float32, len(req.Input)),
+	}
+
+	// Only inputs missing from the cache go to the API
+	var missing []int
+	for i, input := range req.Input {
+		if c.embeddingCache != nil {
+			if vec, ok := c.embeddingCache.Get(model, req.Dimensions, input); ok {
+				result.Embeddings[i] = vec
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+
+	for start := 0; start < len(missing); start += MaxEmbeddingBatch {
+		batch := missing[start:min(start+MaxEmbeddingBatch, len(missing))]
+		inputs := make([]string, len(batch))
+		for j, idx := range batch {
+			inputs[j] = req.Input[idx]
+		}
+
+		resp, err := c.embedBatch(ctx, &EmbeddingRequest{Model: model, Input: inputs, Dimensions: req.Dimensions})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != len(inputs) {
+			return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(resp.Data), len(inputs))
+		}
+		useIndex := indicesArePermutation(resp)
+		for j, d := range resp.Data {
+			pos := j
+			if useIndex {
+				pos = d.Index
+			}
+			result.Embeddings[batch[pos]] = d.Embedding
+		}
+		if c.embeddingCache != nil {
+			for _, idx := range batch {
+				c.embeddingCache.Put(model, req.Dimensions, req.Input[idx], result.Embeddings[idx])
+			}
+		}
+		result.Usage.Add(resp.Usage)
+	}
+	return result, nil
+}
+
+// indicesArePermutation reports whether the index fields of resp name each
+// input exactly once. Otherwise vectors are taken in response order.
+func indicesArePermutation(resp *embeddingWireResponse) bool {
+	seen := make([]bool, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(seen) || seen[d.Index] {
+			return false
+		}
+		seen[d.Index] = true
+	}
+	return true
+}
+
+func (c *Client) embedBatch(ctx context.Context, req *EmbeddingRequest) (*embeddingWireResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	resp, err := c.do(ctx, "POST", c.baseURL+"/embeddings", body, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embResp embeddingWireResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &embResp, nil
+}
+
+// EmbeddingCache stores vectors on disk keyed by a hash of model, dimensions
+// and text, so unchanged content is never embedded twice
+type EmbeddingCache struct {
+	dir string
+}
+
+// NewEmbeddingCache creates a cache rooted at dir
+func NewEmbeddingCache(dir string) *EmbeddingCache {
+	return &EmbeddingCache{dir: dir}
+}
+
+// DefaultEmbeddingCacheDir returns ~/.golem/embeddings
+func DefaultEmbeddingCacheDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".golem", "embeddings")
+}
+
+// WithEmbeddingCache serves repeated Embeddings inputs from cache
+func WithEmbeddingCache(cache *EmbeddingCache) Option {
+	return func(c *Client) {
+		c.embeddingCache = cache
+	}
+}
+
+func (ec *EmbeddingCache) path(model string, dimensions int, text string) string {
+	h := sha256.New()
+	h.Write([]byte(model + "\x00" + strconv.Itoa(dimensions) + "\x00"))
+	h.Write([]byte(text))
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(ec.dir, key[:2], key)
+}
+
+// Get returns the cached vector for text, if any
+func (ec *EmbeddingCache) Get(model string, dimensions int, text string) ([]float32, bool) {
+	data, err := os.ReadFile(ec.path(model, dimensions, text))
+	if err != nil || len(data)%4 != 0 {
+		return nil, false
+	}
+	vec := make([]float32, len(data)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vec, true
+}
+
+// Put stores a vector. Errors are returned but callers may ignore them:
+// the cache is an optimisation only.
+func (ec *EmbeddingCache) Put(model string, dimensions int, text string, vec []float32) error {
+	path := ec.path(model, dimensions, text)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	// Write a temp file of our own, then rename, so neither a concurrent
+	// reader nor another writer ever sees a partial vector
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// embeddingServer answers with vectors [len(text), dimensions] in reverse
+// index order, and records the batch sizes it saw
+func embeddingServer(t *testing.T, batches *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		*batches = append(*batches, len(req.Input))
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(req.Input[i])), float32(req.Dimensions)}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": req.Model,
+			"data":  data,
+			"usage": Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)},
+		})
+	}))
+}
+
+func TestEmbeddingsBatching(t *testing.T) {
+	var batches []int
+	server := embeddingServer(t, &batches)
+	defer server.Close()
+
+	inputs := make([]string, MaxEmbeddingBatch+6)
+	for i := range inputs {
+		inputs[i] = string(make([]byte, i))
+	}
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	resp, err := c.Embeddings(context.Background(), &EmbeddingRequest{Input: inputs, Dimensions: 256})
+	if err != nil {
+		t.Fatalf("Embeddings failed: %v", err)
+	}
+	if len(batches) != 2 || batches[0] != MaxEmbeddingBatch || batches[1] != 6 {
+		t.Errorf("batches = %v, want [%d 6]", batches, MaxEmbeddingBatch)
+	}
+	for i, vec := range resp.Embeddings {
+		if vec[0] != float32(i) || vec[1] != 256 {
+			t.Fatalf("embedding %d = %v, want [%d 256]", i, vec, i)
+		}
+	}
+	if resp.Usage.TotalTokens != len(inputs) {
+		t.Errorf("usage = %d, want %d", resp.Usage.TotalTokens, len(inputs))
+	}
+}
+
+func TestEmbeddingsCache(t *testing.T) {
+	var batches []int
+	server := embeddingServer(t, &batches)
+	defer server.Close()
+
+	c := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithEmbeddingCache(NewEmbeddingCache(t.TempDir())),
+	)
+	ctx := context.Background()
+	if _, err := c.Embeddings(ctx, &EmbeddingRequest{Input: []string{"a", "bb"}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Embeddings(ctx, &EmbeddingRequest{Input: []string{"bb", "ccc", "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || batches[1] != 1 {
+		t.Errorf("batches = %v, want only the new input sent the second time", batches)
+	}
+	for i, want := range []float32{2, 3, 1} {
+		if resp.Embeddings[i][0] != want {
+			t.Errorf("embedding %d = %v, want length %v", i, resp.Embeddings[i], want)
+		}
+	}
+	if resp.Usage.TotalTokens != 1 {
+		t.Errorf("usage = %d, want 1", resp.Usage.TotalTokens)
+	}
+}
+
+func TestEmbeddingsBadIndices(t *testing.T) {
+	for name, indices := range map[string]string{
+		"missing":   `{"embedding":[1]},{"embedding":[2]},{"embedding":[3]}`,
+		"duplicate": `{"index":0,"embedding":[1]},{"index":2,"embedding":[2]},{"index":2,"embedding":[3]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"data":[` + indices + `]}`))
+			}))
+			defer server.Close()
+
+			cache := NewEmbeddingCache(t.TempDir())
+			c := NewClient("test-key", WithBaseURL(server.URL), WithEmbeddingCache(cache))
+			inputs := []string{"a", "b", "c"}
+			resp, err := c.Embeddings(context.Background(), &EmbeddingRequest{Input: inputs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Vectors fall back to response order, in the result and the cache
+			for i, input := range inputs {
+				if got := resp.Embeddings[i]; len(got) != 1 || got[0] != float32(i+1) {
+					t.Errorf("embedding %d = %v, want [%d]", i, got, i+1)
+				}
+				if got, ok := cache.Get(ModelEmbedding3, 0, input); !ok || got[0] != float32(i+1) {
+					t.Errorf("cached %q = %v, %v", input, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddingChecksStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":"1210","message":"bad input"}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	if _, err := c.Embedding(context.Background(), "text", ""); err == nil {
+		t.Fatal("expected error for HTTP 400")
+	}
+}
+
+func TestEmbeddingCacheConcurrentPut(t *testing.T) {
+	cache := NewEmbeddingCache(t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.Put(ModelEmbedding3, 0, "same", []float32{1, 2, 3}); err != nil {
+				t.Errorf("Put: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, ok := cache.Get(ModelEmbedding3, 0, "same"); !ok || len(got) != 3 {
+		t.Errorf("Get = %v, %v", got, ok)
+	}
+}
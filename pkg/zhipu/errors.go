@@ -0,0 +1,150 @@
+package zhipu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error categories returned by the API. Match them with errors.Is:
+//
+//	if errors.Is(err, zhipu.ErrAuth) { ... }
+var (
+	ErrAuth            = errors.New("authentication failed")
+	ErrQuotaExceeded   = errors.New("quota exhausted")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrContentFiltered = errors.New("content blocked by safety filter")
+	ErrContextLength   = errors.New("context length exceeded")
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrServer          = errors.New("server error")
+)
+
+// Provider error codes grouped by category. Numeric codes come from Z.AI,
+// string codes from OpenAI-compatible endpoints.
+var errorCodeCategories = map[string]error{
+	"1000": ErrAuth,          // authentication failed
+	"1001": ErrAuth,          // missing auth header
+	"1002": ErrAuth,          // invalid token
+	"1003": ErrAuth,          // token expired
+	"1004": ErrAuth,          // token rejected
+	"1113": ErrQuotaExceeded, // insufficient balance
+	"1304": ErrQuotaExceeded, // daily call limit reached
+	"1302": ErrRateLimited,   // concurrency limit
+	"1303": ErrRateLimited,   // request frequency limit
+	"1305": ErrRateLimited,   // service overloaded
+	"1301": ErrContentFiltered,
+	"1261": ErrContextLength, // prompt too long
+
+	"invalid_api_key":         ErrAuth,
+	"insufficient_quota":      ErrQuotaExceeded,
+	"rate_limit_exceeded":     ErrRateLimited,
+	"content_filter":          ErrContentFiltered,
+	"context_length_exceeded": ErrContextLength,
+}
+
+// APIError is returned for any non-2xx response from the API
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       string // provider error code, e.g. "1302"
+	Message    string // provider error message
+	RequestID  string // request ID for correlating with provider logs
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error %d", e.StatusCode)
+	if e.Code != "" {
+		msg += "/" + e.Code
+	}
+	msg += ": " + e.Message
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Is reports whether the error belongs to the given category sentinel
+func (e *APIError) Is(target error) bool {
+	return e.Category() == target
+}
+
+// Category returns the sentinel error describing this failure
+func (e *APIError) Category() error {
+	if category, ok := errorCodeCategories[e.Code]; ok {
+		return category
+	}
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	case strings.Contains(strings.ToLower(e.Message), "context length"):
+		return ErrContextLength
+	}
+	return ErrInvalidRequest
+}
+
+// Temporary reports whether retrying the same request may succeed
+func (e *APIError) Temporary() bool {
+	return isRetryable(e.StatusCode, e.Code)
+}
+
+// errorCode accepts codes sent either as JSON strings or numbers
+type errorCode string
+
+func (c *errorCode) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" { // OpenAI-compatible endpoints often send no code
+		*c = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = errorCode(s)
+		return nil
+	}
+	*c = errorCode(strings.TrimSpace(string(data)))
+	return nil
+}
+
+// apiErrorBody is the error envelope returned by the API
+type apiErrorBody struct {
+	RequestID string `json:"request_id"`
+	Error     struct {
+		Code    errorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"error"`
+}
+
+// ParseAPIError builds the APIError for a failed response from its status,
+// headers and body. header may be nil.
+func ParseAPIError(status int, header http.Header, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status}
+	var payload apiErrorBody
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
+		apiErr.Code = string(payload.Error.Code)
+		apiErr.Message = payload.Error.Message
+		apiErr.RequestID = payload.RequestID
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(status)
+		}
+	}
+	if apiErr.RequestID == "" && header != nil {
+		apiErr.RequestID = header.Get("X-Request-Id")
+	}
+	return apiErr
+}
+
+// withRequestID tags an APIError that carries no request ID with the one the
+// caller sent, so the failure can still be found in provider logs
+func withRequestID(err error, requestID string) error {
+	var apiErr *APIError
+	if requestID != "" && errors.As(err, &apiErr) && apiErr.RequestID == "" {
+		apiErr.RequestID = requestID
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIErrorCategories(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{401, `{"error":{"code":"1002","message":"invalid token"}}`, ErrAuth},
+		{429, `{"error":{"code":"1113","message":"insufficient balance"}}`, ErrQuotaExceeded},
+		{429, `{"error":{"code":"1302","message":"concurrency too high"}}`, ErrRateLimited},
+		{400, `{"error":{"code":"1301","message":"unsafe content"}}`, ErrContentFiltered},
+		{400, `{"error":{"code":1261,"message":"prompt too long"}}`, ErrContextLength},
+		{400, `{"error":{"code":"context_length_exceeded","message":"too long"}}`, ErrContextLength},
+		{400, `{"error":{"code":"1214","message":"bad parameter"}}`, ErrInvalidRequest},
+		{502, `bad gateway`, ErrServer},
+		{401, `{"error":{"code":null,"message":"Incorrect API key provided"}}`, ErrAuth},
+	}
+
+	for _, tt := range tests {
+		err := ParseAPIError(tt.status, nil, []byte(tt.body))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("ParseAPIError(%d, %s) category = %v, want %v", tt.status, tt.body, err.Category(), tt.want)
+		}
+	}
+}
+
+func TestChatReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-42")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"code":"1000","message":"authentication failed"}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("bad-key")
+	c.baseURL = server.URL
+
+	_, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "1000" {
+		t.Errorf("status/code = %d/%s, want 401/1000", apiErr.StatusCode, apiErr.Code)
+	}
+	if apiErr.RequestID != "req-42" {
+		t.Errorf("RequestID = %q, want %q", apiErr.RequestID, "req-42")
+	}
+	if !errors.Is(err, ErrAuth) {
+		t.Error("expected errors.Is(err, ErrAuth)")
+	}
+}
+
+func TestAPIErrorFallsBackToSentRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":"1210","message":"bad parameter"}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	_, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B, RequestID: "golem-7"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.RequestID != "golem-7" {
+		t.Errorf("RequestID = %q, want %q", apiErr.RequestID, "golem-7")
+	}
+}
@@ -0,0 +1,200 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// File purposes
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeFileExtract = "file-extract"
+	FilePurposeFineTune    = "fine-tune"
+	FilePurposeRetrieval   = "retrieval"
+)
+
+// File is an uploaded file
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+// FileList is one page of files
+type FileList struct {
+	Data    []File `json:"data"`
+	HasMore bool   `json:"has_more"`
+}
+
+// ListFilesRequest filters and pages ListFiles; all fields are optional
+type ListFilesRequest struct {
+	Purpose string
+	After   string // Last file ID of the previous page
+	Limit   int
+}
+
+// UploadFileRequest describes a file to upload. Content is streamed, so it
+// is read exactly once and never held in memory as a whole.
+type UploadFileRequest struct {
+	Filename string
+	Purpose  string
+	Content  io.Reader
+}
+
+// UploadFile uploads a file as a streamed multipart body. Because the body
+// cannot be replayed, uploads are not retried, and only ctx bounds their
+// duration (large files routinely exceed the request timeout).
+func (c *Client) UploadFile(ctx context.Context, req *UploadFileRequest) (*File, error) {
+	var file File
+	err := c.uploadMultipart(ctx, c.baseURL+"/files", func(mw *multipart.Writer) error {
+		if err := mw.WriteField("purpose", req.Purpose); err != nil {
+			return err
+		}
+		return writeFormFile(mw, "file", req.Filename, req.Content)
+	}, &file)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", req.Filename, err)
+	}
+	return &file, nil
+}
+
+// uploadMultipart streams a multipart body produced by write through a pipe,
+// so nothing is buffered, and decodes the JSON reply into out
+func (c *Client) uploadMultipart(ctx context.Context, url string, write func(*multipart.Writer) error, out interface{}) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := write(mw)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	httpReq, err := c.newHTTPRequest(ctx, "POST", url, pr, mw.FormDataContentType(), "")
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return ParseAPIError(resp.StatusCode, resp.Header, data)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// writeFormFile copies content into a file part of a multipart form
+func writeFormFile(mw *multipart.Writer, field, filename string, content io.Reader) error {
+	part, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return fmt.Errorf("read %s: %w", filename, err)
+	}
+	return nil
+}
+
+// UploadFilePath uploads a file from disk
+func (c *Client) UploadFilePath(ctx context.Context, path, purpose string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return c.UploadFile(ctx, &UploadFileRequest{
+		Filename: filepath.Base(path),
+		Purpose:  purpose,
+		Content:  f,
+	})
+}
+
+// ListFiles lists uploaded files
+func (c *Client) ListFiles(ctx context.Context, req *ListFilesRequest) (*FileList, error) {
+	q := url.Values{}
+	if req != nil {
+		if req.Purpose != "" {
+			q.Set("purpose", req.Purpose)
+		}
+		if req.After != "" {
+			q.Set("after", req.After)
+		}
+		if req.Limit > 0 {
+			q.Set("limit", strconv.Itoa(req.Limit))
+		}
+	}
+	path := "/files"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var list FileList
+	if err := c.getJSON(ctx, "GET", path, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetFile returns the metadata of an uploaded file
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	var file File
+	if err := c.getJSON(ctx, "GET", "/files/"+url.PathEscape(fileID), &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DeleteFile removes an uploaded file
+func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
+	var result struct {
+		Deleted bool `json:"deleted"`
+	}
+	if err := c.getJSON(ctx, "DELETE", "/files/"+url.PathEscape(fileID), &result); err != nil {
+		return err
+	}
+	if !result.Deleted {
+		return fmt.Errorf("file %s was not deleted", fileID)
+	}
+	return nil
+}
+
+// FileContent downloads a file, e.g. the output of a finished batch.
+// The caller must close the returned reader.
+func (c *Client) FileContent(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, "GET", c.baseURL+"/files/"+url.PathEscape(fileID)+"/content", nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getJSON sends a bodiless request and decodes the JSON reply into out
+func (c *Client) getJSON(ctx context.Context, method, path string, out interface{}) error {
+	resp, err := c.do(ctx, method, c.baseURL+path, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
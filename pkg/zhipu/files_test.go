@@ -0,0 +1,108 @@
+package zhipu
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileStreams(t *testing.T) {
+	content := strings.Repeat("0123456789", 100_000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != -1 {
+			t.Errorf("ContentLength = %d, want -1 (streamed)", r.ContentLength)
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("multipart reader: %v", err)
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			switch part.FormName() {
+			case "purpose":
+				if string(data) != FilePurposeRetrieval {
+					t.Errorf("purpose = %q", data)
+				}
+			case "file":
+				if part.FileName() != "doc.txt" || string(data) != content {
+					t.Errorf("file %q: got %d bytes, want %d", part.FileName(), len(data), len(content))
+				}
+			}
+		}
+		w.Write([]byte(`{"id":"file-1","filename":"doc.txt","bytes":1000000}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	file, err := c.UploadFile(context.Background(), &UploadFileRequest{
+		Filename: "doc.txt",
+		Purpose:  FilePurposeRetrieval,
+		Content:  strings.NewReader(content),
+	})
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if file.ID != "file-1" {
+		t.Errorf("ID = %q, want file-1", file.ID)
+	}
+}
+
+func TestUploadFileNotRetried(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	_, err := c.UploadFile(context.Background(), &UploadFileRequest{Filename: "a", Content: strings.NewReader("x")})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestFileManagement(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/files":
+			if r.URL.Query().Get("purpose") != FilePurposeBatch || r.URL.Query().Get("limit") != "2" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"data":[{"id":"f1"},{"id":"f2"}],"has_more":true}`))
+		case r.Method == "GET" && r.URL.Path == "/files/f1":
+			w.Write([]byte(`{"id":"f1","filename":"in.jsonl","purpose":"batch"}`))
+		case r.Method == "DELETE" && r.URL.Path == "/files/f1":
+			w.Write([]byte(`{"id":"f1","deleted":true}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	ctx := context.Background()
+
+	list, err := c.ListFiles(ctx, &ListFilesRequest{Purpose: FilePurposeBatch, Limit: 2})
+	if err != nil || len(list.Data) != 2 || !list.HasMore {
+		t.Errorf("ListFiles = %+v, %v", list, err)
+	}
+	file, err := c.GetFile(ctx, "f1")
+	if err != nil || file.Filename != "in.jsonl" {
+		t.Errorf("GetFile = %+v, %v", file, err)
+	}
+	if err := c.DeleteFile(ctx, "f1"); err != nil {
+		t.Errorf("DeleteFile failed: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Image qualities. HD is slower and only supported by CogView-4.
+const (
+	ImageQualityStandard = "standard"
+	ImageQualityHD       = "hd"
+)
+
+// DefaultImageSize is used when ImageRequest.Size is empty
+const DefaultImageSize = "1024x1024"
+
+// ImageRequest describes images to generate
+type ImageRequest struct {
+	Model   string `json:"model"`
+	Prompt  string `json:"prompt"`
+	Size    string `json:"size,omitempty"`    // WxH, multiples of 16 between 512 and 2048
+	Quality string `json:"quality,omitempty"` // ImageQualityStandard or ImageQualityHD
+	N       int    `json:"-"`                 // Number of images; defaults to 1
+	UserID  string `json:"user_id,omitempty"`
+}
+
+// Image is one generated image. URL is temporary and should be downloaded
+// promptly.
+type Image struct {
+	URL string `json:"url"`
+}
+
+// ImageResponse holds the generated images
+type ImageResponse struct {
+	Created       int64           `json:"created"`
+	Data          []Image         `json:"data"`
+	ContentFilter []ContentFilter `json:"content_filter,omitempty"`
+}
+
+// ContentFilter reports moderation applied to a prompt or result
+type ContentFilter struct {
+	Role  string `json:"role"`
+	Level int    `json:"level"`
+}
+
+// ImageGenerate generates images from a prompt. The API returns one image
+// per call, so N > 1 sends N requests and merges the results.
+func (c *Client) ImageGenerate(ctx context.Context, req *ImageRequest) (*ImageResponse, error) {
+	r := *req
+	if r.Model == "" {
+		r.Model = ModelCogView4
+	}
+	if r.Size == "" {
+		r.Size = DefaultImageSize
+	}
+	body, err := json.Marshal(&r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	n := max(r.N, 1)
+	var out ImageResponse
+	for i := 0; i < n; i++ {
+		resp, err := c.do(ctx, "POST", c.baseURL+"/images/generations", body, "")
+		if err != nil {
+			return nil, err
+		}
+		var page ImageResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		if out.Created == 0 {
+			out.Created = page.Created
+		}
+		out.Data = append(out.Data, page.Data...)
+		out.ContentFilter = append(out.ContentFilter, page.ContentFilter...)
+	}
+	return &out, nil
+}
+
+// DownloadImage fetches a generated image. The caller must close the
+// returned reader.
+func (c *Client) DownloadImage(ctx context.Context, img Image) (io.ReadCloser, error) {
+	// Image URLs are pre-signed, so the API key is deliberately not sent
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", img.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download image: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
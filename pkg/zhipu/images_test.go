@@ -0,0 +1,59 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImageGenerate(t *testing.T) {
+	calls := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/generations":
+			calls++
+			var req map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["model"] != ModelCogView4 || req["size"] != "768x1344" || req["quality"] != ImageQualityHD {
+				t.Errorf("request = %v", req)
+			}
+			if _, ok := req["n"]; ok {
+				t.Error("n must not be sent")
+			}
+			w.Write([]byte(`{"created":1,"data":[{"url":"` + server.URL + `/img.png"}]}`))
+		case "/img.png":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("image download must not send the API key")
+			}
+			w.Write([]byte("PNG"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	resp, err := c.ImageGenerate(context.Background(), &ImageRequest{
+		Prompt:  "a golem",
+		Size:    "768x1344",
+		Quality: ImageQualityHD,
+		N:       2,
+	})
+	if err != nil {
+		t.Fatalf("ImageGenerate failed: %v", err)
+	}
+	if calls != 2 || len(resp.Data) != 2 {
+		t.Fatalf("calls = %d, images = %d, want 2 and 2", calls, len(resp.Data))
+	}
+
+	body, err := c.DownloadImage(context.Background(), resp.Data[0])
+	if err != nil {
+		t.Fatalf("DownloadImage failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "PNG" {
+		t.Errorf("image = %q", data)
+	}
+}
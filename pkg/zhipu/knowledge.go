@@ -0,0 +1,210 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// KnowledgeEmbedding3 is the embedding model id for new knowledge bases
+const KnowledgeEmbedding3 = 3
+
+// Knowledge is a knowledge base the Retrieval tool answers from
+type Knowledge struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	EmbeddingID  int    `json:"embedding_id"`
+	DocumentSize int    `json:"document_size"`
+	WordNum      int    `json:"word_num"`
+	Length       int64  `json:"length"`
+}
+
+// CreateKnowledgeRequest describes a new knowledge base
+type CreateKnowledgeRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	EmbeddingID int    `json:"embedding_id"` // Defaults to KnowledgeEmbedding3
+}
+
+// KnowledgeDocument is a document indexed in a knowledge base
+type KnowledgeDocument struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Length    int64  `json:"length"`
+	WordNum   int    `json:"word_num"`
+	Embedding struct {
+		Status  int    `json:"embedding_stat"`
+		FailMsg string `json:"failInfo"`
+	} `json:"embeddingStat"`
+}
+
+// DocumentUpload is one file to add to a knowledge base
+type DocumentUpload struct {
+	Filename string
+	Content  io.Reader
+}
+
+// DocumentUploadResult reports which uploaded documents were accepted
+type DocumentUploadResult struct {
+	Succeeded []struct {
+		DocumentID string `json:"documentId"`
+		Filename   string `json:"fileName"`
+	} `json:"successInfos"`
+	Failed []struct {
+		Filename string `json:"fileName"`
+		Reason   string `json:"failReason"`
+	} `json:"failedInfos"`
+}
+
+// NewRetrievalTool lets the model answer from a knowledge base. The prompt
+// template may use {{knowledge}} and {{question}}; empty uses the default.
+func NewRetrievalTool(knowledgeID, promptTemplate string) Tool {
+	return Tool{
+		Type:      "retrieval",
+		Retrieval: &Retrieval{KnowledgeID: knowledgeID, PromptTemplate: promptTemplate},
+	}
+}
+
+// knowledgeEnvelope wraps every knowledge API reply; failures may arrive
+// with HTTP 200 and a non-200 code
+type knowledgeEnvelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// CreateKnowledge creates a knowledge base
+func (c *Client) CreateKnowledge(ctx context.Context, req *CreateKnowledgeRequest) (*Knowledge, error) {
+	r := *req
+	if r.EmbeddingID == 0 {
+		r.EmbeddingID = KnowledgeEmbedding3
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.knowledgeCall(ctx, "POST", "/knowledge", &r, &created); err != nil {
+		return nil, err
+	}
+	return &Knowledge{ID: created.ID, Name: r.Name, Description: r.Description, EmbeddingID: r.EmbeddingID}, nil
+}
+
+// ListKnowledge returns one page (from 1) of knowledge bases and the total
+func (c *Client) ListKnowledge(ctx context.Context, page, size int) ([]Knowledge, int, error) {
+	var list struct {
+		List  []Knowledge `json:"list"`
+		Total int         `json:"total"`
+	}
+	if err := c.knowledgeCall(ctx, "GET", "/knowledge?"+pageQuery(page, size).Encode(), nil, &list); err != nil {
+		return nil, 0, err
+	}
+	return list.List, list.Total, nil
+}
+
+// DeleteKnowledge deletes a knowledge base and its documents
+func (c *Client) DeleteKnowledge(ctx context.Context, knowledgeID string) error {
+	return c.knowledgeCall(ctx, "DELETE", "/knowledge/"+url.PathEscape(knowledgeID), nil, nil)
+}
+
+// UploadDocuments adds documents to a knowledge base in one streamed
+// multipart request. Indexing continues server-side after it returns.
+func (c *Client) UploadDocuments(ctx context.Context, knowledgeID string, docs ...DocumentUpload) (*DocumentUploadResult, error) {
+	var env knowledgeEnvelope
+	endpoint := c.knowledgeEndpoint() + "/document/upload_document/" + url.PathEscape(knowledgeID)
+	err := c.uploadMultipart(ctx, endpoint, func(mw *multipart.Writer) error {
+		for _, doc := range docs {
+			if err := writeFormFile(mw, "files", doc.Filename, doc.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &env)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DocumentUploadResult
+	if err := env.decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListDocuments returns one page (from 1) of the documents in a knowledge base
+func (c *Client) ListDocuments(ctx context.Context, knowledgeID string, page, size int) ([]KnowledgeDocument, int, error) {
+	q := pageQuery(page, size)
+	q.Set("knowledge_id", knowledgeID)
+	var list struct {
+		List  []KnowledgeDocument `json:"list"`
+		Total int                 `json:"total"`
+	}
+	if err := c.knowledgeCall(ctx, "GET", "/document?"+q.Encode(), nil, &list); err != nil {
+		return nil, 0, err
+	}
+	return list.List, list.Total, nil
+}
+
+// DeleteDocument removes a document from its knowledge base
+func (c *Client) DeleteDocument(ctx context.Context, documentID string) error {
+	return c.knowledgeCall(ctx, "DELETE", "/document/"+url.PathEscape(documentID), nil, nil)
+}
+
+func (c *Client) knowledgeCall(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+	}
+	resp, err := c.do(ctx, method, c.knowledgeEndpoint()+path, body, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env knowledgeEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return env.decode(out)
+}
+
+// decode checks the envelope code and unmarshals data into out (if non-nil)
+func (e *knowledgeEnvelope) decode(out interface{}) error {
+	if e.Code != 0 && e.Code != 200 {
+		return &APIError{StatusCode: 200, Code: strconv.Itoa(e.Code), Message: e.Message}
+	}
+	if out == nil || len(e.Data) == 0 || string(e.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(e.Data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func pageQuery(page, size int) url.Values {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+	return url.Values{"page": {strconv.Itoa(page)}, "size": {strconv.Itoa(size)}}
+}
+
+// knowledgeEndpoint returns the knowledge API root, which lives next to the
+// paas/v4 API
+func (c *Client) knowledgeEndpoint() string {
+	if c.knowledgeURL != "" {
+		return c.knowledgeURL
+	}
+	return strings.TrimSuffix(c.baseURL, "/paas/v4") + "/llm-application/open"
+}
@@ -0,0 +1,125 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKnowledgeEndpoint(t *testing.T) {
+	c := NewClient("test-key")
+	if got, want := c.knowledgeEndpoint(), "https://api.z.ai/api/llm-application/open"; got != want {
+		t.Errorf("knowledgeEndpoint() = %q, want %q", got, want)
+	}
+	c = NewClient("test-key", WithKnowledgeURL("http://kb.local/"))
+	if got := c.knowledgeEndpoint(); got != "http://kb.local" {
+		t.Errorf("override = %q", got)
+	}
+}
+
+func TestKnowledgeManagement(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/knowledge":
+			var req CreateKnowledgeRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Name != "design" || req.EmbeddingID != KnowledgeEmbedding3 {
+				t.Errorf("create request = %+v", req)
+			}
+			w.Write([]byte(`{"code":200,"message":"ok","data":{"id":"kb-1"}}`))
+		case r.Method == "GET" && r.URL.Path == "/knowledge":
+			if r.URL.Query().Get("page") != "1" || r.URL.Query().Get("size") != "10" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"code":200,"data":{"list":[{"id":"kb-1","name":"design","document_size":3}],"total":1}}`))
+		case r.Method == "DELETE" && r.URL.Path == "/knowledge/kb-missing":
+			w.Write([]byte(`{"code":10002,"message":"knowledge not found"}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithKnowledgeURL(server.URL))
+	ctx := context.Background()
+
+	kb, err := c.CreateKnowledge(ctx, &CreateKnowledgeRequest{Name: "design"})
+	if err != nil {
+		t.Fatalf("CreateKnowledge failed: %v", err)
+	}
+	if kb.ID != "kb-1" {
+		t.Errorf("ID = %q, want kb-1", kb.ID)
+	}
+
+	list, total, err := c.ListKnowledge(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("ListKnowledge failed: %v", err)
+	}
+	if total != 1 || len(list) != 1 || list[0].DocumentSize != 3 {
+		t.Errorf("list = %+v, total %d", list, total)
+	}
+
+	err = c.DeleteKnowledge(ctx, "kb-missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "10002" {
+		t.Fatalf("err = %v, want APIError with code 10002", err)
+	}
+}
+
+func TestUploadDocuments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/document/upload_document/kb-1" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("multipart reader: %v", err)
+		}
+		var names []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if part.FormName() != "files" {
+				t.Errorf("field = %q, want files", part.FormName())
+			}
+			names = append(names, part.FileName())
+		}
+		if strings.Join(names, ",") != "a.md,b.md" {
+			t.Errorf("files = %v", names)
+		}
+		w.Write([]byte(`{"code":200,"data":{"successInfos":[{"documentId":"d1","fileName":"a.md"}],"failedInfos":[{"fileName":"b.md","failReason":"empty file"}]}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithKnowledgeURL(server.URL))
+	result, err := c.UploadDocuments(context.Background(), "kb-1",
+		DocumentUpload{Filename: "a.md", Content: strings.NewReader("# A")},
+		DocumentUpload{Filename: "b.md", Content: strings.NewReader("")},
+	)
+	if err != nil {
+		t.Fatalf("UploadDocuments failed: %v", err)
+	}
+	if len(result.Succeeded) != 1 || result.Succeeded[0].DocumentID != "d1" {
+		t.Errorf("succeeded = %+v", result.Succeeded)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Reason != "empty file" {
+		t.Errorf("failed = %+v", result.Failed)
+	}
+}
+
+func TestRetrievalToolJSON(t *testing.T) {
+	data, err := json.Marshal(NewRetrievalTool("kb-1", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"type":"retrieval","retrieval":{"knowledge_id":"kb-1"}}`; got != want {
+		t.Errorf("tool = %s, want %s", got, want)
+	}
+}
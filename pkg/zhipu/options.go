@@ -0,0 +1,187 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// BigModelBaseURL is the mainland China endpoint (open.bigmodel.cn)
+	BigModelBaseURL = "https://open.bigmodel.cn/api/paas/v4"
+
+	// DefaultTimeout bounds a non-streaming request from send to last byte
+	DefaultTimeout = 120 * time.Second
+	// DefaultStreamIdleTimeout bounds the gap between two chunks of a stream
+	DefaultStreamIdleTimeout = 60 * time.Second
+)
+
+// ErrStreamIdle is returned when a stream sends nothing for longer than the
+// configured idle timeout
+var ErrStreamIdle = errors.New("stream idle timeout")
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at another endpoint, e.g. BigModelBaseURL,
+// a corporate gateway or a local test server
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithAgentsURL overrides the hosted agents endpoint, which by default is
+// derived from the base URL
+func WithAgentsURL(url string) Option {
+	return func(c *Client) {
+		c.agentsURL = url
+	}
+}
+
+// WithKnowledgeURL overrides the knowledge base API root, which by default
+// is derived from the base URL
+func WithKnowledgeURL(url string) Option {
+	return func(c *Client) {
+		c.knowledgeURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithContextGuard turns the pre-flight context window check of Chat,
+// ChatStream and ChatAsync on or off. It is on by default.
+func WithContextGuard(enabled bool) Option {
+	return func(c *Client) {
+		c.contextGuard = enabled
+	}
+}
+
+// WithHTTPClient replaces the underlying HTTP client (transport, proxy, TLS).
+// Any Timeout set on it applies to streams too; prefer WithTimeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout bounds each non-streaming request (0 disables the limit)
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithStreamIdleTimeout aborts a stream when no data arrives for d.
+// Streams have no total time limit. 0 disables the idle check.
+func WithStreamIdleTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.streamIdleTimeout = d
+	}
+}
+
+// WithHeader adds a header sent with every request
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.headers.Set("User-Agent", ua)
+	}
+}
+
+// WithRetryPolicy replaces the default retry policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// BaseURL returns the endpoint the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// agentsEndpoint returns the hosted agents URL, which lives next to the
+// paas/v4 API on both Z.AI and BigModel
+func (c *Client) agentsEndpoint() string {
+	if c.agentsURL != "" {
+		return c.agentsURL
+	}
+	return strings.TrimSuffix(c.baseURL, "/paas/v4") + "/v1/agents"
+}
+
+// attemptContext derives the context for one HTTP attempt. Plain requests get
+// a total deadline; streams get an idle timer that body reads keep pushing back.
+func (c *Client) attemptContext(ctx context.Context, stream bool) (context.Context, context.CancelCauseFunc, *time.Timer) {
+	if !stream && c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		return ctx, func(error) { cancel() }, nil
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	if !stream || c.streamIdleTimeout <= 0 {
+		return ctx, cancel, nil
+	}
+	idle := time.AfterFunc(c.streamIdleTimeout, func() { cancel(ErrStreamIdle) })
+	return ctx, cancel, idle
+}
+
+// WatchStream derives the context of a streaming request made without a
+// Client, which is cancelled with ErrStreamIdle once no data arrives for
+// idleTimeout (0 disables the timer). Pass the response body to wrap:
+// reads from the wrapped body push the deadline back and closing it
+// releases the context. Call stop instead when there is no body to wrap.
+func WatchStream(ctx context.Context, idleTimeout time.Duration) (streamCtx context.Context, wrap func(io.ReadCloser) io.ReadCloser, stop func()) {
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	var idle *time.Timer
+	if idleTimeout > 0 {
+		idle = time.AfterFunc(idleTimeout, func() { cancel(ErrStreamIdle) })
+	}
+	wrap = func(body io.ReadCloser) io.ReadCloser {
+		return &deadlineBody{ReadCloser: body, ctx: streamCtx, cancel: cancel, idle: idle, idleTimeout: idleTimeout}
+	}
+	stop = func() {
+		if idle != nil {
+			idle.Stop()
+		}
+		cancel(nil)
+	}
+	return streamCtx, wrap, stop
+}
+
+// deadlineBody releases the attempt context when closed and, for streams,
+// extends the idle deadline on every read
+type deadlineBody struct {
+	io.ReadCloser
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	idle        *time.Timer
+	idleTimeout time.Duration
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.idle != nil && n > 0 {
+		b.idle.Reset(b.idleTimeout)
+	}
+	if err != nil && err != io.EOF {
+		if cause := context.Cause(b.ctx); errors.Is(cause, ErrStreamIdle) {
+			return n, cause
+		}
+	}
+	return n, err
+}
+
+func (b *deadlineBody) Close() error {
+	if b.idle != nil {
+		b.idle.Stop()
+	}
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
@@ -0,0 +1,100 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Team"); got != "golem" {
+			t.Errorf("X-Team = %q, want %q", got, "golem")
+		}
+		if got := r.Header.Get("User-Agent"); got != "golem-test/1.0" {
+			t.Errorf("User-Agent = %q, want %q", got, "golem-test/1.0")
+		}
+		switch r.URL.Path {
+		case "/api/paas/v4/chat/completions":
+			w.Write([]byte(`{"id":"chat"}`))
+		case "/api/v1/agents":
+			w.Write([]byte(`{"id":"agent"}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key",
+		WithBaseURL(server.URL+"/api/paas/v4/"),
+		WithHeader("X-Team", "golem"),
+		WithUserAgent("golem-test/1.0"),
+	)
+
+	if _, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	resp, err := c.AgentChat(context.Background(), &AgentChatRequest{AgentID: "a1"})
+	if err != nil {
+		t.Fatalf("AgentChat failed: %v", err)
+	}
+	if resp.ID != "agent" {
+		t.Errorf("agent response ID = %q, want %q", resp.ID, "agent")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithTimeout(20*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{}),
+	)
+
+	if _, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B}); err == nil {
+		t.Fatal("expected timeout error")
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	// The total timeout must not apply to streams, only the idle timeout
+	c := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithTimeout(time.Millisecond),
+		WithStreamIdleTimeout(50*time.Millisecond),
+	)
+
+	stream, err := c.ChatStream(context.Background(), &ChatRequest{Model: ModelGLM4_32B})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	result := CollectStream(stream)
+	if result.Content != "Hi" {
+		t.Errorf("text = %q, want %q", result.Content, "Hi")
+	}
+	if !errors.Is(result.Error, ErrStreamIdle) {
+		t.Errorf("err = %v, want ErrStreamIdle", result.Error)
+	}
+}
@@ -0,0 +1,217 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries throttled or failed requests.
+// Retries are only attempted before a response body is handed to the caller,
+// so a stream is never replayed once tokens have been emitted.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt (0 disables retries)
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on each attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff as well as any Retry-After hint
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// Z.AI error codes that signal temporary throttling
+var retryableCodes = map[string]bool{
+	"1302": true, // concurrency limit
+	"1303": true, // request frequency limit
+	"1305": true, // service overloaded
+}
+
+// Z.AI error codes returned with 429 that will not clear by waiting
+var exhaustedCodes = map[string]bool{
+	"1113": true, // insufficient balance
+	"1304": true, // daily call limit reached
+}
+
+// SetRetryPolicy replaces the client's retry policy
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// backoff returns the jittered delay before retry number attempt (0-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	// Double per attempt, stopping at MaxDelay and before d overflows
+	d := p.BaseDelay
+	for i := 0; i < attempt && d > 0 && d <= math.MaxInt64/2; i++ {
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Equal jitter: keep half of the delay, randomize the rest
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Delay returns the wait before retry number attempt (0-based). It honours
+// a Retry-After header value when present, capped at MaxDelay, and falls
+// back to the jittered backoff.
+func (p RetryPolicy) Delay(attempt int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return d
+	}
+	return p.backoff(attempt)
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// isRetryable reports whether a failed response is worth another attempt
+func isRetryable(status int, code string) bool {
+	if retryableCodes[code] {
+		return true
+	}
+	switch status {
+	case http.StatusTooManyRequests:
+		return !exhaustedCodes[code]
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends an authenticated request, retrying according to the client's policy.
+// On success the caller owns resp.Body and must close it; on failure the body
+// has been consumed and turned into an error. Requests that accept
+// text/event-stream are treated as streams: no total timeout, idle timeout only.
+func (c *Client) do(ctx context.Context, method, url string, body []byte, accept string) (*http.Response, error) {
+	stream := accept == "text/event-stream"
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+	}
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		attemptCtx, cancel, idle := c.attemptContext(ctx, stream)
+		httpReq, err := c.newHTTPRequest(attemptCtx, method, url, reader, contentType, accept)
+		if err != nil {
+			cancel(nil)
+			return nil, err
+		}
+
+		var wait time.Duration
+		resp, err := c.httpClient.Do(httpReq)
+		switch {
+		case err != nil:
+			if cause := context.Cause(attemptCtx); errors.Is(cause, ErrStreamIdle) {
+				err = cause
+			}
+			cancel(nil)
+			if ctx.Err() != nil || attempt >= c.retry.MaxRetries {
+				return nil, fmt.Errorf("do request: %w", err)
+			}
+			wait = c.retry.backoff(attempt)
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			resp.Body = &deadlineBody{
+				ReadCloser:  resp.Body,
+				ctx:         attemptCtx,
+				cancel:      cancel,
+				idle:        idle,
+				idleTimeout: c.streamIdleTimeout,
+			}
+			return resp, nil
+		default:
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			cancel(nil)
+			apiErr := ParseAPIError(resp.StatusCode, resp.Header, data)
+			if attempt >= c.retry.MaxRetries || !apiErr.Temporary() {
+				return nil, apiErr
+			}
+			wait = c.retry.Delay(attempt, resp.Header.Get("Retry-After"))
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newHTTPRequest builds an authenticated request carrying the client headers
+func (c *Client) newHTTPRequest(ctx context.Context, method, url string, body io.Reader, contentType, accept string) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	for key, values := range c.headers {
+		httpReq.Header[key] = values
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if accept != "" {
+		httpReq.Header.Set("Accept", accept)
+	}
+	return httpReq, nil
+}
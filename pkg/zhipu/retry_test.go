@@ -0,0 +1,135 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func TestChatRetriesOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"ok","choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key")
+	c.baseURL = server.URL
+	c.SetRetryPolicy(fastRetryPolicy())
+
+	resp, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.ID != "ok" {
+		t.Errorf("response ID = %q, want %q", resp.ID, "ok")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestChatRetriesRateLimitCode(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":"1302","message":"concurrency too high"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key")
+	c.baseURL = server.URL
+	c.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestChatDoesNotRetryExhaustedQuota(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":"1113","message":"insufficient balance"}}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key")
+	c.baseURL = server.URL
+	c.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := c.Chat(context.Background(), &ChatRequest{Model: ModelGLM4_32B}); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryHonoursContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key")
+	c.baseURL = server.URL
+	c.SetRetryPolicy(RetryPolicy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Chat(ctx, &ChatRequest{Model: ModelGLM4_32B}); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("retry ignored context cancellation (took %v)", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("2"); !ok || d != 2*time.Second {
+		t.Errorf("parseRetryAfter(\"2\") = %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter(""); ok {
+		t.Error("empty Retry-After should not parse")
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 0 {
+		t.Errorf("parseRetryAfter(date) = %v, %v", d, ok)
+	}
+}
+
+func TestBackoffLargeAttempt(t *testing.T) {
+	p := DefaultRetryPolicy()
+	for _, attempt := range []int{0, 5, 63, 64, 1000} {
+		if d := p.backoff(attempt); d <= 0 || d > p.MaxDelay {
+			t.Errorf("backoff(%d) = %v, want in (0, %v]", attempt, d, p.MaxDelay)
+		}
+	}
+	p.MaxDelay = 0
+	if d := p.backoff(1000); d <= 0 {
+		t.Errorf("backoff(1000) without MaxDelay = %v, want > 0", d)
+	}
+}
@@ -0,0 +1,387 @@
+package zhipu
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StreamEventType identifies the kind of a StreamEvent
+type StreamEventType int
+
+const (
+	EventTextDelta      StreamEventType = iota + 1 // Text holds answer text
+	EventReasoningDelta                            // Text holds reasoning text
+	EventToolCallDelta                             // ToolCall holds a fragment of call ToolCallIndex
+	EventToolCall                                  // ToolCall holds a complete call
+	EventUsage                                     // Usage holds token counts
+	EventFinish                                    // FinishReason is set
+	EventWebSearch                                 // WebSearch holds consulted pages
+	EventError                                     // Err is set; always the last event
+)
+
+// String returns the event type name
+func (t StreamEventType) String() string {
+	switch t {
+	case EventTextDelta:
+		return "text"
+	case EventReasoningDelta:
+		return "reasoning"
+	case EventToolCallDelta:
+		return "tool_call_delta"
+	case EventToolCall:
+		return "tool_call"
+	case EventUsage:
+		return "usage"
+	case EventFinish:
+		return "finish"
+	case EventWebSearch:
+		return "web_search"
+	case EventError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// StreamEvent is one typed increment of a streamed reply
+type StreamEvent struct {
+	Type          StreamEventType
+	Text          string
+	ToolCallIndex int
+	ToolCall      *ToolCall
+	Usage         *Usage
+	FinishReason  string
+	WebSearch     []WebSearchResult
+	Err           error
+}
+
+// Stream yields the events of a streamed chat completion in order.
+// It reads the response synchronously, so no goroutine is left behind;
+// call Close when done, or to abandon the stream early.
+//
+//	stream, err := client.ChatStream(ctx, req)
+//	if err != nil { ... }
+//	defer stream.Close()
+//	for stream.Next() {
+//		ev := stream.Event()
+//		...
+//	}
+//	if err := stream.Err(); err != nil { ... }
+type Stream struct {
+	body     io.ReadCloser
+	scanner  *bufio.Scanner
+	pending  []StreamEvent
+	event    StreamEvent
+	builders map[int]*toolCallBuilder
+	usage    *Usage
+	reqID    string
+	convID   string
+	err      error
+	done     bool
+
+	// decode parses one data payload; endpoints with their own chunk
+	// format convert it to a streamChunk
+	decode func(payload []byte) (*streamChunk, error)
+
+	// decodeLine is set for streams in another vendor's format: one
+	// payload per line, or per SSE data line when sse is set
+	decodeLine LineDecoder
+	sse        bool
+
+	closeOnce sync.Once
+	closeMu   sync.Mutex
+	closed    bool
+}
+
+// maxStreamLine bounds a single SSE line (large tool arguments arrive in one line)
+const maxStreamLine = 1 << 20
+
+// NewStream reads a chat completion event stream from body. Besides Z.AI it
+// decodes any OpenAI-compatible endpoint, whose chunks have the same shape.
+// The stream owns body and closes it when done.
+func NewStream(body io.ReadCloser, requestID string) *Stream {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	return &Stream{
+		body:     body,
+		scanner:  scanner,
+		builders: make(map[int]*toolCallBuilder),
+		reqID:    requestID,
+		decode:   decodeChatChunk,
+	}
+}
+
+// LineDecoder converts one payload of another vendor's stream to events.
+// Tool calls may be sent as EventToolCallDelta fragments keyed by
+// ToolCallIndex; the stream joins them into EventToolCall events before
+// EventFinish. A nil result with a nil error skips the payload.
+type LineDecoder func(payload []byte) ([]StreamEvent, error)
+
+// NewLineStream reads a newline-delimited JSON stream, such as Ollama's,
+// from body. decode turns each non-empty line into events; an error it
+// returns ends the stream with an EventError. EventUsage events also set
+// Usage. The stream owns body and closes it when done.
+func NewLineStream(body io.ReadCloser, requestID string, decode LineDecoder) *Stream {
+	s := NewStream(body, requestID)
+	s.decodeLine = decode
+	return s
+}
+
+// NewSSEStream reads a server-sent event stream whose data payloads are in
+// another vendor's format. decode gets each payload without the "data:"
+// prefix, as for NewLineStream; a "[DONE]" payload ends the stream.
+func NewSSEStream(body io.ReadCloser, requestID string, decode LineDecoder) *Stream {
+	s := NewLineStream(body, requestID, decode)
+	s.sse = true
+	return s
+}
+
+// DecodeChatChunk converts one OpenAI-compatible stream chunk to events,
+// for decoders of vendors that wrap that format
+func DecodeChatChunk(payload []byte) ([]StreamEvent, error) {
+	chunk, err := decodeChatChunk(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := chunk.err(""); err != nil {
+		return nil, err
+	}
+	return chunk.events(), nil
+}
+
+func decodeChatChunk(payload []byte) (*streamChunk, error) {
+	var chunk streamChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
+
+// Next advances to the next event. It returns false once the stream has
+// ended, failed or been closed.
+func (s *Stream) Next() bool {
+	for len(s.pending) == 0 {
+		if s.done {
+			return false
+		}
+		s.fill()
+	}
+	s.event, s.pending = s.pending[0], s.pending[1:]
+	return true
+}
+
+// Event returns the event Next advanced to
+func (s *Stream) Event() StreamEvent {
+	return s.event
+}
+
+// Err returns the error that ended the stream, if any
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Usage returns the token counts reported by the final chunk, or nil if the
+// API sent none. It is complete once Next has returned false.
+func (s *Stream) Usage() *Usage {
+	return s.usage
+}
+
+// RequestID returns the request ID reported by the API, or the one sent with
+// the request until the first chunk arrives
+func (s *Stream) RequestID() string {
+	return s.reqID
+}
+
+// ConversationID returns the conversation a hosted agent stream belongs to,
+// to continue it with the next AgentChatRequest. It is empty for chat
+// completion streams.
+func (s *Stream) ConversationID() string {
+	return s.convID
+}
+
+// Close releases the HTTP response. It is safe to call more than once and
+// from another goroutine while Next is blocked.
+func (s *Stream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.closeMu.Lock()
+		s.closed = true
+		s.closeMu.Unlock()
+		err = s.body.Close()
+	})
+	return err
+}
+
+func (s *Stream) isClosed() bool {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	return s.closed
+}
+
+// fill reads one SSE line and queues the events it carries
+func (s *Stream) fill() {
+	if !s.scanner.Scan() {
+		err := s.scanner.Err()
+		s.flushToolCalls()
+		if err != nil && !s.isClosed() {
+			s.fail(err)
+			return
+		}
+		s.finish()
+		return
+	}
+
+	line := strings.TrimSpace(s.scanner.Text())
+	payload := line
+	if s.decodeLine == nil || s.sse {
+		if !strings.HasPrefix(line, "data:") {
+			return
+		}
+		payload = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			s.flushToolCalls()
+			s.finish()
+			return
+		}
+	}
+	if payload == "" {
+		return
+	}
+
+	if s.decodeLine != nil {
+		events, err := s.decodeLine([]byte(payload))
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		s.handleEvents(events)
+		return
+	}
+	chunk, err := s.decode([]byte(payload))
+	if err != nil {
+		return
+	}
+	s.handleChunk(chunk)
+}
+
+func (s *Stream) handleChunk(chunk *streamChunk) {
+	if chunk.RequestID != "" {
+		s.reqID = chunk.RequestID
+	}
+	if chunk.ConversationID != "" {
+		s.convID = chunk.ConversationID
+	}
+	if err := chunk.err(s.reqID); err != nil {
+		s.fail(err)
+		return
+	}
+	s.handleEvents(chunk.events())
+}
+
+// err returns the error an in-stream error chunk reports, or nil
+func (chunk *streamChunk) err(requestID string) error {
+	if chunk.Error == nil || chunk.Error.Message == "" {
+		return nil
+	}
+	return &APIError{
+		StatusCode: 200,
+		Code:       string(chunk.Error.Code),
+		Message:    chunk.Error.Message,
+		RequestID:  requestID,
+	}
+}
+
+// events converts a chunk to events, with tool calls as fragments
+func (chunk *streamChunk) events() []StreamEvent {
+	var events []StreamEvent
+	if len(chunk.WebSearch) > 0 {
+		events = append(events, StreamEvent{Type: EventWebSearch, WebSearch: chunk.WebSearch})
+	}
+
+	for _, choice := range chunk.Choices {
+		if text := choice.Delta.ReasoningContent + choice.Message.ReasoningContent; text != "" {
+			events = append(events, StreamEvent{Type: EventReasoningDelta, Text: text})
+		}
+		if text := choice.Delta.Content + choice.Message.Content; text != "" {
+			events = append(events, StreamEvent{Type: EventTextDelta, Text: text})
+		}
+
+		// Tool calls are streamed as fragments keyed by index
+		for _, tcDelta := range choice.Delta.ToolCalls {
+			var fragment toolCallBuilder
+			fragment.add(tcDelta)
+			call := fragment.build()
+			events = append(events, StreamEvent{Type: EventToolCallDelta, ToolCallIndex: tcDelta.Index, ToolCall: &call})
+		}
+
+		if choice.FinishReason != "" {
+			events = append(events, StreamEvent{Type: EventFinish, FinishReason: choice.FinishReason})
+		}
+	}
+
+	if chunk.Usage != nil {
+		events = append(events, StreamEvent{Type: EventUsage, Usage: chunk.Usage})
+	}
+	return events
+}
+
+// handleEvents queues events, joining tool call fragments into whole
+// calls that are emitted before the finish event
+func (s *Stream) handleEvents(events []StreamEvent) {
+	for _, ev := range events {
+		switch ev.Type {
+		case EventToolCallDelta:
+			b, exists := s.builders[ev.ToolCallIndex]
+			if !exists {
+				b = &toolCallBuilder{}
+				s.builders[ev.ToolCallIndex] = b
+			}
+			b.addCall(ev.ToolCall)
+		case EventFinish:
+			s.flushToolCalls()
+		case EventUsage:
+			s.usage = ev.Usage
+		case EventError:
+			s.fail(ev.Err)
+			return
+		}
+		s.emit(ev)
+	}
+}
+
+// flushToolCalls emits accumulated tool calls in index order
+func (s *Stream) flushToolCalls() {
+	if len(s.builders) == 0 {
+		return
+	}
+	indexes := make([]int, 0, len(s.builders))
+	for idx := range s.builders {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	for _, idx := range indexes {
+		if b := s.builders[idx]; b.complete() {
+			call := b.build()
+			s.emit(StreamEvent{Type: EventToolCall, ToolCallIndex: idx, ToolCall: &call})
+		}
+	}
+	s.builders = make(map[int]*toolCallBuilder)
+}
+
+func (s *Stream) emit(ev StreamEvent) {
+	s.pending = append(s.pending, ev)
+}
+
+func (s *Stream) fail(err error) {
+	s.err = err
+	s.emit(StreamEvent{Type: EventError, Err: err})
+	s.finish()
+}
+
+func (s *Stream) finish() {
+	s.done = true
+	s.Close()
+}
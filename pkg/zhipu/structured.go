@@ -0,0 +1,281 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResponseFormat types
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json_object"
+)
+
+// ResponseFormat constrains the shape of the reply
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// JSONMode asks the model to reply with a single JSON object
+func JSONMode() *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSON}
+}
+
+// ErrInvalidOutput is returned by ChatJSON when the model keeps replying with
+// JSON that does not match the schema
+var ErrInvalidOutput = errors.New("reply does not match schema")
+
+// maxJSONRepairs bounds how often ChatJSON re-prompts with a validation error
+const maxJSONRepairs = 2
+
+// ChatClient is the part of Client that ChatJSON needs
+type ChatClient interface {
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// ChatJSON asks for a JSON reply and decodes it into T. The schema generated
+// from T is included in the prompt and every reply is validated against it;
+// on a mismatch the model is shown the error and asked again, a bounded
+// number of times. See SchemaFor for the struct tags that shape the schema.
+func ChatJSON[T any](ctx context.Context, c ChatClient, req *ChatRequest) (T, error) {
+	var out T
+	schema := SchemaFor(out)
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return out, fmt.Errorf("marshal schema: %w", err)
+	}
+
+	r := *req
+	r.Stream = false
+	r.ResponseFormat = JSONMode()
+	r.Messages = append([]Message{{
+		Role:    "system",
+		Content: "Reply with a single JSON value, without commentary or code fences, matching this JSON schema:\n" + string(schemaJSON),
+	}}, req.Messages...)
+
+	var lastErr error
+	for attempt := 0; attempt <= maxJSONRepairs; attempt++ {
+		resp, err := c.Chat(ctx, &r)
+		if err != nil {
+			return out, err
+		}
+		if len(resp.Choices) == 0 {
+			return out, fmt.Errorf("no completion")
+		}
+		content, _ := resp.Choices[0].Message.Content.(string)
+
+		if lastErr = decodeJSONReply(content, schema, &out); lastErr == nil {
+			return out, nil
+		}
+		r.Messages = append(r.Messages,
+			Message{Role: "assistant", Content: content},
+			Message{Role: "user", Content: "That reply is invalid: " + lastErr.Error() + ". Reply again with only the corrected JSON."},
+		)
+	}
+	return out, fmt.Errorf("%w after %d attempts: %v", ErrInvalidOutput, maxJSONRepairs+1, lastErr)
+}
+
+// decodeJSONReply validates a reply against schema and decodes it into out
+func decodeJSONReply(content string, schema *JSONSchema, out interface{}) error {
+	data := []byte(extractJSON(content))
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("not valid JSON: %v", err)
+	}
+	if err := schema.Validate(v); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// extractJSON strips what models like to wrap JSON in: a <think> block,
+// markdown fences or a sentence of preamble
+func extractJSON(content string) string {
+	s := strings.TrimSpace(content)
+	if i := strings.LastIndex(s, "</think>"); i >= 0 {
+		s = strings.TrimSpace(s[i+len("</think>"):])
+	}
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```json")
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+		s = strings.TrimSpace(s)
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start >= 0 && end > start {
+		return s[start : end+1]
+	}
+	return s
+}
+
+// SchemaFor derives a JSON schema from the type of v. Struct fields use their
+// json names; fields without omitempty are required. Two extra tags are read:
+//
+//	Verdict string `json:"verdict" description:"Overall outcome" enum:"approve,request_changes"`
+func SchemaFor(v interface{}) *JSONSchema {
+	return schemaForType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaForType tracks the structs being described in visiting, so a
+// recursive type ends in a plain object instead of recursing forever
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	if t == nil {
+		return &JSONSchema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: schemaForType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object"}
+	case reflect.Struct:
+		if visiting[t] {
+			return &JSONSchema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return schemaForStruct(t, visiting)
+	}
+	return &JSONSchema{}
+}
+
+func schemaForStruct(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	schema := NewObjectSchema(map[string]*JSONSchema{}, nil)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := schemaForType(f.Type, visiting)
+		prop.Description = f.Tag.Get("description")
+		if enum := f.Tag.Get("enum"); enum != "" {
+			prop.Enum = strings.Split(enum, ",")
+		}
+		schema.Properties[name] = prop
+
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// Validate checks a decoded JSON value (as produced by json.Unmarshal into
+// interface{}) against the schema. It supports the subset of JSON schema
+// that JSONSchema can express.
+func (s *JSONSchema) Validate(v interface{}) error {
+	return s.validate("$", v)
+}
+
+func (s *JSONSchema) validate(path string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %s", path, jsonKind(v))
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			if val, ok := obj[name]; ok && val != nil {
+				if err := s.Properties[name].validate(path+"."+name, val); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %s", path, jsonKind(v))
+		}
+		for i, item := range arr {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %s", path, jsonKind(v))
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %s", path, str, strings.Join(s.Enum, ", "))
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer, got %s", path, jsonKind(v))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %s", path, jsonKind(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %s", path, jsonKind(v))
+		}
+	}
+	return nil
+}
+
+// jsonKind names the JSON type of a decoded value for error messages
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,168 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testVerdict struct {
+	Verdict string   `json:"verdict" enum:"approve,reject"`
+	Score   int      `json:"score" description:"0-10"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(testVerdict{})
+	if schema.Type != "object" {
+		t.Fatalf("type = %q, want object", schema.Type)
+	}
+	if got := strings.Join(schema.Required, ","); got != "verdict,score" {
+		t.Errorf("required = %q, want %q", got, "verdict,score")
+	}
+	if got := schema.Properties["verdict"].Enum; len(got) != 2 || got[1] != "reject" {
+		t.Errorf("verdict enum = %v", got)
+	}
+	if got := schema.Properties["score"]; got.Type != "integer" || got.Description != "0-10" {
+		t.Errorf("score = %+v", got)
+	}
+	if got := schema.Properties["notes"]; got.Type != "array" || got.Items.Type != "string" {
+		t.Errorf("notes = %+v", got)
+	}
+}
+
+type testNode struct {
+	Name     string      `json:"name"`
+	Children []*testNode `json:"children,omitempty"`
+	Sibling  testLeaf    `json:"sibling"`
+}
+
+type testLeaf struct {
+	Value string `json:"value"`
+}
+
+func TestSchemaForRecursive(t *testing.T) {
+	schema := SchemaFor(testNode{})
+	children := schema.Properties["children"]
+	if children == nil || children.Type != "array" || children.Items.Type != "object" {
+		t.Fatalf("children = %+v", children)
+	}
+	if len(children.Items.Properties) != 0 {
+		t.Errorf("recursive items = %+v, want a plain object", children.Items)
+	}
+	// A struct used twice without recursion is still described in full
+	if got := schema.Properties["sibling"]; got.Properties["value"] == nil {
+		t.Errorf("sibling = %+v", got)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := SchemaFor(testVerdict{})
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"valid", `{"verdict":"approve","score":7}`, ""},
+		{"missing field", `{"verdict":"approve"}`, `missing required field "score"`},
+		{"bad enum", `{"verdict":"maybe","score":1}`, `$.verdict: "maybe" is not one of`},
+		{"wrong type", `{"verdict":"approve","score":"high"}`, "$.score: expected integer, got string"},
+		{"bad item", `{"verdict":"approve","score":1,"notes":[1]}`, "$.notes[0]: expected string"},
+		{"not object", `[1]`, "$: expected object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.input), &v); err != nil {
+				t.Fatal(err)
+			}
+			err := schema.Validate(v)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                       `{"a":1}`,
+		"```json\n{\"a\":1}\n```":       `{"a":1}`,
+		"<think>hmm</think>\n{\"a\":1}": `{"a":1}`,
+		"Here you go: {\"a\":1} Enjoy.": `{"a":1}`,
+	}
+	for in, want := range tests {
+		if got := extractJSON(in); got != want {
+			t.Errorf("extractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChatJSONRepairs(t *testing.T) {
+	replies := []string{
+		`{"verdict":"maybe","score":3}`,
+		"```json\n{\"verdict\":\"approve\",\"score\":9}\n```",
+	}
+	var requests []ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		reply := replies[len(requests)-1]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	got, err := ChatJSON[testVerdict](context.Background(), c, &ChatRequest{
+		Model:    ModelGLM4_32B,
+		Messages: []Message{{Role: "user", Content: "Judge this"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatJSON failed: %v", err)
+	}
+	if got.Verdict != "approve" || got.Score != 9 {
+		t.Errorf("got %+v", got)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if requests[0].ResponseFormat == nil || requests[0].ResponseFormat.Type != ResponseFormatJSON {
+		t.Errorf("response_format = %+v, want json_object", requests[0].ResponseFormat)
+	}
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	if content, _ := last.Content.(string); !strings.Contains(content, "is not one of") {
+		t.Errorf("repair prompt = %q, want validation error", content)
+	}
+}
+
+func TestChatJSONGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"not json"}}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	_, err := ChatJSON[testVerdict](context.Background(), c, &ChatRequest{Model: ModelGLM4_32B})
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("err = %v, want ErrInvalidOutput", err)
+	}
+	if calls != maxJSONRepairs+1 {
+		t.Errorf("calls = %d, want %d", calls, maxJSONRepairs+1)
+	}
+}
@@ -0,0 +1,179 @@
+package zhipu
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode"
+)
+
+// Per-item overheads used by the estimators. They are on the high side so
+// that a request estimated to fit rarely fails for length.
+const (
+	messageOverheadTokens = 4    // Role markers around each message
+	imageTokens           = 1600 // A vision input at MaxImageSide
+)
+
+// safetyMarginPercent is added to estimates before they are compared with
+// a context window, by checkContext and FitWindow. The estimates are a
+// heuristic and can be short of the real count on text unlike the usual
+// prose, code and Chinese, such as long runs of rare characters.
+const safetyMarginPercent = 10
+
+// withMargin adds the safety margin to an estimate
+func withMargin(tokens int) int {
+	return tokens + tokens*safetyMarginPercent/100
+}
+
+// contextWindows are the context sizes of known models, in tokens
+var contextWindows = map[string]int{
+	ModelGLM4_32B:        128000,
+	ModelGLM4_9B:         128000,
+	ModelGLMZ1_32B:       128000,
+	ModelGLMZ1Rumination: 128000,
+	ModelGLMZ1_9B:        32000,
+	ModelGLM4V:           8192,
+	ModelGLM4VPlus:       16000,
+	ModelGLM4VThinking:   64000,
+	ModelCodeGeeX4:       32000,
+}
+
+// ContextWindow returns the context size of model in tokens, or 0 if unknown
+func ContextWindow(model string) int {
+	return contextWindows[model]
+}
+
+// ContextLengthError reports a request estimated not to fit its model's
+// context window. It matches ErrContextLength with errors.Is.
+type ContextLengthError struct {
+	Model  string
+	Tokens int // Estimated prompt tokens with the safety margin, plus MaxTokens
+	Limit  int
+}
+
+func (e *ContextLengthError) Error() string {
+	return fmt.Sprintf("request needs about %d tokens but %s has a %d-token context window", e.Tokens, e.Model, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrContextLength) true
+func (e *ContextLengthError) Is(target error) bool {
+	return target == ErrContextLength
+}
+
+// EstimateTokens returns a heuristic estimate of the GLM token count of
+// text; it does not run GLM's tokenizer. It splits text the way BPE
+// pre-tokenizers do and prices each piece by how GLM's vocabulary
+// typically encodes it: common words are one token, long words and numbers
+// take several, and each CJK character is counted as one, which is on the
+// high side.
+func EstimateTokens(text string) int {
+	tokens := 0
+	word, digits := 0, 0
+	flush := func() {
+		tokens += (word + 5) / 6
+		tokens += (digits + 2) / 3
+		word, digits = 0, 0
+	}
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '_' || r == '\''):
+			if digits > 0 {
+				flush()
+			}
+			word++
+		case unicode.IsDigit(r):
+			if word > 0 {
+				flush()
+			}
+			digits++
+		case unicode.IsSpace(r):
+			flush()
+			if r == '\n' {
+				tokens++
+			}
+		default:
+			// Punctuation, symbols, CJK and other scripts
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimateMessages estimates the prompt tokens of messages, with the same
+// heuristic as EstimateTokens
+func EstimateMessages(messages []Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += messageOverheadTokens + EstimateTokens(msg.Text())
+		tokens += msg.ImageCount() * imageTokens
+		for _, tc := range msg.ToolCalls {
+			tokens += EstimateTokens(tc.Function.Name) + EstimateTokens(tc.Function.Arguments)
+		}
+	}
+	return tokens
+}
+
+// EstimateRequest estimates the prompt tokens of req, tool definitions included
+func EstimateRequest(req *ChatRequest) int {
+	tokens := EstimateMessages(req.Messages)
+	if len(req.Tools) > 0 {
+		if data, err := json.Marshal(req.Tools); err == nil {
+			tokens += EstimateTokens(string(data))
+		}
+	}
+	return tokens
+}
+
+// checkContext fails before any network call when req, estimated with the
+// safety margin, cannot fit its model's window. Unknown models are not
+// checked.
+func (c *Client) checkContext(req *ChatRequest) error {
+	limit := ContextWindow(req.Model)
+	if !c.contextGuard || limit == 0 {
+		return nil
+	}
+	if tokens := withMargin(EstimateRequest(req)) + req.MaxTokens; tokens > limit {
+		return &ContextLengthError{Model: req.Model, Tokens: tokens, Limit: limit}
+	}
+	return nil
+}
+
+// FitMessages drops the oldest messages until their estimate, with the
+// safety margin, fits model's window with reserve tokens left for the
+// reply. System messages and the last
+// message are always kept, and tool results are never left without the
+// assistant message that called them. It returns the kept messages and
+// how many were dropped.
+func FitMessages(messages []Message, model string, reserve int) ([]Message, int) {
+	return FitWindow(messages, ContextWindow(model), reserve)
+}
+
+// FitWindow is FitMessages for a window of limit tokens, for models this
+// package does not know. A limit of 0 keeps every message.
+func FitWindow(messages []Message, limit, reserve int) ([]Message, int) {
+	if limit == 0 || withMargin(EstimateMessages(messages))+reserve <= limit {
+		return messages, 0
+	}
+
+	var system, rest []Message
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+	budget := limit - reserve
+	systemTokens := EstimateMessages(system)
+	dropped := 0
+	for len(rest) > 1 && withMargin(systemTokens+EstimateMessages(rest)) > budget {
+		rest = rest[1:]
+		dropped++
+		for len(rest) > 1 && rest[0].Role == "tool" {
+			rest = rest[1:]
+			dropped++
+		}
+	}
+	return append(system, rest...), dropped
+}
@@ -0,0 +1,141 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text     string
+		min, max int
+	}{
+		{"", 0, 0},
+		{"hello world", 2, 3},
+		{"func main() { fmt.Println(\"hi\") }", 10, 20},
+		{"上下文窗口", 4, 6},
+		{"2025", 1, 2},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got < tt.min || got > tt.max {
+			t.Errorf("EstimateTokens(%q) = %d, want %d-%d", tt.text, got, tt.min, tt.max)
+		}
+	}
+}
+
+// TestEstimateTokensExact pins the heuristic, so a change to it shows up
+// here rather than as requests that trim too much or too little
+func TestEstimateTokensExact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"english", "The quick brown fox jumps over the lazy dog.", 10},
+		{"english with long words", "Summarize the changes in this pull request and list any breaking API changes.", 19},
+		{"cjk", "请帮我总结一下这个文件的主要内容。", 17},
+		{"code", "func main() {\n\tfmt.Println(\"hello, world\")\n}\n", 20},
+		{"tool arguments", `{"path":"internal/tools/parser.go","offset":120,"limit":40}`, 28},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("%s: EstimateTokens(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateMessagesToolCall(t *testing.T) {
+	args := `{"path":"internal/tools/parser.go","offset":120,"limit":40}`
+	msg := Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Type: "function"}}}
+	msg.ToolCalls[0].Function.Name = "read_file"
+	msg.ToolCalls[0].Function.Arguments = args
+	// Role overhead, then the name (one nine-letter word) and the arguments
+	if got, want := EstimateMessages([]Message{msg}), 4+2+28; got != want {
+		t.Errorf("EstimateMessages() = %d, want %d", got, want)
+	}
+}
+
+func TestFitWindowMargin(t *testing.T) {
+	// 1000 tokens of messages fit a 1000-token window only without the margin
+	messages := []Message{
+		{Role: "user", Content: strings.Repeat("word ", 496)},
+		{Role: "user", Content: strings.Repeat("word ", 496)},
+	}
+	if got := EstimateMessages(messages); got != 1000 {
+		t.Fatalf("EstimateMessages() = %d, want 1000", got)
+	}
+	if _, dropped := FitWindow(messages, 1000, 0); dropped != 1 {
+		t.Errorf("dropped %d messages, want 1 for the safety margin", dropped)
+	}
+	if _, dropped := FitWindow(messages, 1100, 0); dropped != 0 {
+		t.Errorf("dropped %d messages that fit with the margin", dropped)
+	}
+}
+
+func TestChatContextGuard(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	req := &ChatRequest{
+		Model:    ModelGLM4V,
+		Messages: []Message{{Role: "user", Content: strings.Repeat("token ", 9000)}},
+	}
+
+	_, err := c.Chat(context.Background(), req)
+	var lenErr *ContextLengthError
+	if !errors.As(err, &lenErr) || !errors.Is(err, ErrContextLength) {
+		t.Fatalf("err = %v, want a ContextLengthError", err)
+	}
+	if lenErr.Limit != 8192 || lenErr.Tokens <= lenErr.Limit {
+		t.Errorf("error = %+v", lenErr)
+	}
+	if _, err := c.ChatStream(context.Background(), req); !errors.Is(err, ErrContextLength) {
+		t.Errorf("ChatStream err = %v, want ErrContextLength", err)
+	}
+	if calls != 0 {
+		t.Errorf("calls = %d, want none before the guard passes", calls)
+	}
+
+	c = NewClient("test-key", WithBaseURL(server.URL), WithContextGuard(false))
+	if _, err := c.Chat(context.Background(), req); err != nil {
+		t.Errorf("guard disabled: %v", err)
+	}
+}
+
+func TestFitMessages(t *testing.T) {
+	long := strings.Repeat("word ", 2000)
+	messages := []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: long},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1"}}},
+		{Role: "tool", ToolCallID: "1", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: "and now?"},
+	}
+
+	kept, dropped := FitMessages(messages, ModelGLM4V, 4096)
+	if dropped == 0 || EstimateMessages(kept)+4096 > ContextWindow(ModelGLM4V) {
+		t.Fatalf("kept %d tokens, dropped %d", EstimateMessages(kept), dropped)
+	}
+	if kept[0].Role != "system" || kept[len(kept)-1].Text() != "and now?" {
+		t.Errorf("system prompt or last message lost: %+v", kept)
+	}
+	for i, msg := range kept {
+		if msg.Role == "tool" && (i == 0 || kept[i-1].Role != "assistant") {
+			t.Errorf("orphaned tool result at %d", i)
+		}
+	}
+
+	if _, dropped := FitMessages(messages, ModelGLM4_32B, 4096); dropped != 0 {
+		t.Errorf("dropped %d messages that fit a 128k window", dropped)
+	}
+}
@@ -0,0 +1,73 @@
+package zhipu
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToolChoice modes
+const (
+	ToolChoiceModeAuto     = "auto"
+	ToolChoiceModeNone     = "none"
+	ToolChoiceModeRequired = "required"
+)
+
+// ToolChoice controls whether the model calls a tool: a mode ("auto",
+// "none", "required") or, when Function is set, one specific function.
+// It marshals to the string or object form the API expects.
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+// ToolChoiceAuto lets the model decide whether to call a tool
+func ToolChoiceAuto() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeAuto}
+}
+
+// ToolChoiceNone forbids tool calls
+func ToolChoiceNone() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeNone}
+}
+
+// ToolChoiceRequired makes the model call at least one tool
+func ToolChoiceRequired() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceModeRequired}
+}
+
+// ForceTool makes the model call the named function
+func ForceTool(name string) *ToolChoice {
+	return &ToolChoice{Function: name}
+}
+
+type toolChoiceObject struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (tc ToolChoice) MarshalJSON() ([]byte, error) {
+	if tc.Function == "" {
+		return json.Marshal(tc.Mode)
+	}
+	obj := toolChoiceObject{Type: "function"}
+	obj.Function.Name = tc.Function
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (tc *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*tc = ToolChoice{Mode: mode}
+		return nil
+	}
+	var obj toolChoiceObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("tool_choice: %w", err)
+	}
+	*tc = ToolChoice{Function: obj.Function.Name}
+	return nil
+}
@@ -0,0 +1,49 @@
+package zhipu
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolChoiceJSON(t *testing.T) {
+	tests := []struct {
+		choice *ToolChoice
+		want   string
+	}{
+		{ToolChoiceAuto(), `"auto"`},
+		{ToolChoiceNone(), `"none"`},
+		{ToolChoiceRequired(), `"required"`},
+		{ForceTool("read_file"), `{"type":"function","function":{"name":"read_file"}}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.choice)
+		if err != nil {
+			t.Fatalf("marshal %+v: %v", tt.choice, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("marshal %+v = %s, want %s", tt.choice, data, tt.want)
+		}
+
+		var decoded ToolChoice
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		if decoded != *tt.choice {
+			t.Errorf("round trip = %+v, want %+v", decoded, *tt.choice)
+		}
+	}
+}
+
+func TestChatRequestToolChoiceOmitted(t *testing.T) {
+	data, err := json.Marshal(&ChatRequest{Model: ModelGLM4_32B})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	for _, key := range []string{"tool_choice", "parallel_tool_calls"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%s should be omitted when unset: %s", key, data)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package zhipu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Image limits for vision input. Larger images are downscaled so the longest
+// side fits MaxImageSide, which also keeps their token cost down.
+const (
+	MaxImageBytes = 5 << 20
+	MaxImageSide  = 2048
+)
+
+// IsVisionModel reports whether model accepts image input
+func IsVisionModel(model string) bool {
+	return strings.HasPrefix(model, "glm-4v") || strings.HasPrefix(model, "glm-4.1v") || strings.HasPrefix(model, "glm-4.5v")
+}
+
+// VisionModelFor returns the vision model to use in place of model when
+// images are attached: model itself if it has vision, the thinking vision
+// model for reasoning models, and GLM-4V-Plus otherwise
+func VisionModelFor(model string) string {
+	switch {
+	case IsVisionModel(model):
+		return model
+	case strings.HasPrefix(model, "glm-z1"):
+		return ModelGLM4VThinking
+	}
+	return ModelGLM4VPlus
+}
+
+// NewImageMessage builds a user message with local images followed by text
+func NewImageMessage(text string, paths ...string) (Message, error) {
+	parts := make([]ContentPart, 0, len(paths)+1)
+	for _, path := range paths {
+		url, err := ImageDataURL(path)
+		if err != nil {
+			return Message{}, err
+		}
+		parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}})
+	}
+	if text != "" {
+		parts = append(parts, ContentPart{Type: "text", Text: text})
+	}
+	return Message{Role: "user", Content: parts}, nil
+}
+
+// ImageDataURL reads a PNG, JPEG or WebP file as a base64 data URL,
+// downscaling it if it exceeds the vision limits
+func ImageDataURL(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	url, err := EncodeImageDataURL(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return url, nil
+}
+
+// EncodeImageDataURL is ImageDataURL for an image already in memory
+func EncodeImageDataURL(data []byte) (string, error) {
+	mime := http.DetectContentType(data)
+	switch mime {
+	case "image/png", "image/jpeg":
+		var err error
+		if data, mime, err = fitImage(data, mime); err != nil {
+			return "", err
+		}
+	case "image/webp":
+		// The standard library has no WebP decoder, so WebP is sent as is
+		if len(data) > MaxImageBytes {
+			return "", fmt.Errorf("WebP image is over %d MB; convert it to PNG or JPEG to downscale it", MaxImageBytes>>20)
+		}
+	default:
+		return "", fmt.Errorf("unsupported image type %s (want PNG, JPEG or WebP)", mime)
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// fitImage downscales and re-encodes an image that is too large. Images are
+// kept in their format unless a PNG stays over MaxImageBytes, in which case
+// it becomes a JPEG.
+func fitImage(data []byte, mime string) ([]byte, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	if len(data) <= MaxImageBytes && max(cfg.Width, cfg.Height) <= MaxImageSide {
+		return data, mime, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	img = downscale(img, MaxImageSide)
+
+	var buf bytes.Buffer
+	if mime == "image/png" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		if buf.Len() <= MaxImageBytes {
+			return buf.Bytes(), mime, nil
+		}
+		buf.Reset()
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// downscale shrinks img so its longest side is at most maxSide, averaging
+// the source pixels that fall into each destination pixel
+func downscale(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if max(w, h) <= maxSide {
+		return img
+	}
+	nw, nh := w*maxSide/max(w, h), h*maxSide/max(w, h)
+	nw, nh = max(nw, 1), max(nh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := b.Min.Y+y*h/nh, b.Min.Y+(y+1)*h/nh
+		for x := 0; x < nw; x++ {
+			x0, x1 := b.Min.X+x*w/nw, b.Min.X+(x+1)*w/nw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// Text returns the text of a message, joining the text parts of
+// multimodal content
+func (m Message) Text() string {
+	if s, ok := m.Content.(string); ok {
+		return s
+	}
+	var texts []string
+	for _, part := range m.parts() {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ImageCount returns the number of images attached to a message
+func (m Message) ImageCount() int {
+	n := 0
+	for _, part := range m.parts() {
+		if part.Type == "image_url" {
+			n++
+		}
+	}
+	return n
+}
+
+// ImageURLs returns the URLs of the images attached to a message, data
+// URLs included
+func (m Message) ImageURLs() []string {
+	var urls []string
+	for _, part := range m.parts() {
+		if part.Type == "image_url" && part.ImageURL != nil {
+			urls = append(urls, part.ImageURL.URL)
+		}
+	}
+	return urls
+}
+
+// HasImages reports whether any message has an image attached
+func HasImages(messages []Message) bool {
+	for _, msg := range messages {
+		if msg.ImageCount() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parts returns multimodal content as ContentParts, including content that
+// was decoded from JSON (e.g. a saved session) into generic values
+func (m Message) parts() []ContentPart {
+	switch c := m.Content.(type) {
+	case []ContentPart:
+		return c
+	case []interface{}:
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil
+		}
+		var parts []ContentPart
+		json.Unmarshal(data, &parts)
+		return parts
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package zhipu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeDataURL(t *testing.T, url string) (string, image.Config) {
+	t.Helper()
+	header, payload, ok := strings.Cut(url, ";base64,")
+	if !ok {
+		t.Fatalf("not a base64 data URL: %.40s", url)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimPrefix(header, "data:"), cfg
+}
+
+func TestEncodeImageDataURL(t *testing.T) {
+	url, err := EncodeImageDataURL(encodePNG(t, 40, 30))
+	if err != nil {
+		t.Fatalf("EncodeImageDataURL failed: %v", err)
+	}
+	mime, cfg := decodeDataURL(t, url)
+	if mime != "image/png" || cfg.Width != 40 || cfg.Height != 30 {
+		t.Errorf("got %s %dx%d, want image/png 40x30", mime, cfg.Width, cfg.Height)
+	}
+
+	if _, err := EncodeImageDataURL([]byte("%PDF-1.7")); err == nil {
+		t.Error("expected error for a non-image")
+	}
+}
+
+func TestEncodeImageDataURLDownscales(t *testing.T) {
+	url, err := EncodeImageDataURL(encodePNG(t, MaxImageSide*2, 100))
+	if err != nil {
+		t.Fatalf("EncodeImageDataURL failed: %v", err)
+	}
+	_, cfg := decodeDataURL(t, url)
+	if cfg.Width != MaxImageSide || cfg.Height != 50 {
+		t.Errorf("size = %dx%d, want %dx50", cfg.Width, cfg.Height, MaxImageSide)
+	}
+}
+
+func TestNewImageMessage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(path, encodePNG(t, 8, 8), 0644); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := NewImageMessage("why is this broken?", path)
+	if err != nil {
+		t.Fatalf("NewImageMessage failed: %v", err)
+	}
+	if msg.Text() != "why is this broken?" || msg.ImageCount() != 1 {
+		t.Errorf("Text() = %q, ImageCount() = %d", msg.Text(), msg.ImageCount())
+	}
+
+	// A message reloaded from a saved session keeps its parts
+	data, _ := json.Marshal(msg)
+	var loaded Message
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Text() != msg.Text() || !HasImages([]Message{loaded}) {
+		t.Errorf("reloaded message lost its parts: %q", loaded.Text())
+	}
+	if urls := loaded.ImageURLs(); len(urls) != 1 || !strings.HasPrefix(urls[0], "data:image/png;base64,") {
+		t.Errorf("ImageURLs() = %v", urls)
+	}
+}
+
+func TestVisionModelFor(t *testing.T) {
+	tests := map[string]string{
+		ModelGLM4_32B:      ModelGLM4VPlus,
+		ModelGLMZ1_32B:     ModelGLM4VThinking,
+		ModelGLM4VThinking: ModelGLM4VThinking,
+		ModelGLM4V:         ModelGLM4V,
+	}
+	for model, want := range tests {
+		if got := VisionModelFor(model); got != want {
+			t.Errorf("VisionModelFor(%s) = %s, want %s", model, got, want)
+		}
+	}
+}
@@ -1,11 +1,25 @@
 package zhipu
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
+)
+
+// Search engines
+const (
+	SearchEngineStd = "search_std"
+	SearchEnginePro = "search_pro"
+)
+
+// Recency filters
+const (
+	RecencyDay   = "oneDay"
+	RecencyWeek  = "oneWeek"
+	RecencyMonth = "oneMonth"
+	RecencyYear  = "oneYear"
+	RecencyAny   = "noLimit"
 )
 
 type WebSearchRequest struct {
@@ -34,32 +48,52 @@ type WebSearchResult struct {
 	PublishDate string `json:"publish_date"`
 }
 
+// WebSearch searches the web. SearchEngine defaults to SearchEngineStd.
 func (c *Client) WebSearch(ctx context.Context, req *WebSearchRequest) (*WebSearchResponse, error) {
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+	r := *req
+	if r.SearchEngine == "" {
+		r.SearchEngine = SearchEngineStd
 	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/web_search", bytes.NewReader(body))
+	body, err := json.Marshal(&r)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(ctx, "POST", c.baseURL+"/web_search", body, "")
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, parseAPIError(resp)
-	}
-
 	var out WebSearchResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 	return &out, nil
 }
+
+// maxSnippet bounds the page text FormatWebSearchResults keeps per result
+const maxSnippet = 400
+
+// FormatWebSearchResults renders results as a compact numbered list the
+// model can cite by number, with each Link on its own line
+func FormatWebSearchResults(results []WebSearchResult) string {
+	if len(results) == 0 {
+		return "No results."
+	}
+	var b strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&b, "[%d] %s", i+1, r.Title)
+		if source := strings.TrimSpace(r.Media + " " + r.PublishDate); source != "" {
+			fmt.Fprintf(&b, " (%s)", source)
+		}
+		fmt.Fprintf(&b, "\n    %s\n", r.Link)
+		if snippet := strings.Join(strings.Fields(r.Content), " "); snippet != "" {
+			if runes := []rune(snippet); len(runes) > maxSnippet {
+				snippet = string(runes[:maxSnippet]) + "…"
+			}
+			fmt.Fprintf(&b, "    %s\n", snippet)
+		}
+	}
+	return b.String()
+}
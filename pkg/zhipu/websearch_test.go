@@ -0,0 +1,46 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSearchDefaultsEngine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req WebSearchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.SearchEngine != SearchEngineStd || req.SearchRecencyFilter != RecencyWeek {
+			t.Errorf("request = %+v", req)
+		}
+		w.Write([]byte(`{"search_result":[{"title":"Go 1.24","link":"https://go.dev/doc/go1.24"}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("test-key", WithBaseURL(server.URL))
+	resp, err := c.WebSearch(context.Background(), &WebSearchRequest{SearchQuery: "go release", SearchRecencyFilter: RecencyWeek})
+	if err != nil {
+		t.Fatalf("WebSearch failed: %v", err)
+	}
+	if len(resp.SearchResult) != 1 {
+		t.Fatalf("results = %+v", resp.SearchResult)
+	}
+}
+
+func TestFormatWebSearchResults(t *testing.T) {
+	got := FormatWebSearchResults([]WebSearchResult{
+		{Title: "Go 1.24", Link: "https://go.dev/doc/go1.24", Media: "go.dev", Content: "Generic\n  type   aliases"},
+		{Title: "Long", Link: "https://example.com", Content: strings.Repeat("x", maxSnippet+10)},
+	})
+	want := "[1] Go 1.24 (go.dev)\n    https://go.dev/doc/go1.24\n    Generic type aliases\n[2] Long\n    https://example.com\n    " +
+		strings.Repeat("x", maxSnippet) + "…\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if FormatWebSearchResults(nil) != "No results." {
+		t.Error("empty results should say so")
+	}
+}